@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/platform/provider"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+var apiHandler web.Handler
+
+func init() {
+	handler, err := provider.Handler(func(s *provider.Services) web.Handler {
+		return rest.NewAPI(s.Creator, s.Getter, s.Manager).Serve
+	})
+	if err != nil {
+		log.Fatalf("initialize api handler: %v", err)
+	}
+	apiHandler = handler
+}
+
+func main() {
+	lambda.Start(web.LambdaHandler(apiHandler))
+}
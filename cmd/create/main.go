@@ -1,32 +1,26 @@
 package main
 
 import (
-	"os"
-	"time"
+	"log"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/projects/secure-notes/internal/creating"
 	"github.com/projects/secure-notes/internal/http/rest"
 	"github.com/projects/secure-notes/internal/platform/provider"
-	"github.com/projects/secure-notes/internal/platform/security"
 	"github.com/projects/secure-notes/internal/platform/web"
 )
 
 var createNoteHandler web.Handler
 
 func init() {
-	cfg := provider.AWSConfig()
-	storage := provider.DynamoStorage(cfg, os.Getenv("NOTES_TABLE"))
-
-	now := func() time.Time { return time.Now().UTC() }
-	hashGen := security.GenerateHashWithSalt
-	creator := creating.NewService(storage, now, hashGen)
-
-	handler := rest.CreateNote(creator)
-	middleware := provider.Middleware()
-	createNoteHandler = middleware.WrapWithCorsAndLogging(handler)
+	handler, err := provider.Handler(func(s *provider.Services) web.Handler {
+		return rest.CreateNote(s.Creator)
+	})
+	if err != nil {
+		log.Fatalf("initialize create note handler: %v", err)
+	}
+	createNoteHandler = handler
 }
 
 func main() {
-	lambda.Start(createNoteHandler)
+	lambda.Start(web.LambdaHandler(createNoteHandler))
 }
@@ -1,10 +1,9 @@
 package main
 
 import (
-	"os"
+	"log"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/projects/secure-notes/internal/getting"
 	"github.com/projects/secure-notes/internal/http/rest"
 	"github.com/projects/secure-notes/internal/platform/provider"
 	"github.com/projects/secure-notes/internal/platform/web"
@@ -13,14 +12,15 @@ import (
 var getNoteHandler web.Handler
 
 func init() {
-	cfg := provider.AWSConfig()
-	storage := provider.DynamoStorage(cfg, os.Getenv("NOTES_TABLE"))
-	getter := getting.NewService(storage)
-	handler := rest.GetNote(getter)
-	middleware := provider.Middleware()
-	getNoteHandler = middleware.WrapWithCorsAndLogging(handler)
+	handler, err := provider.Handler(func(s *provider.Services) web.Handler {
+		return rest.GetNote(s.Getter)
+	})
+	if err != nil {
+		log.Fatalf("initialize get note handler: %v", err)
+	}
+	getNoteHandler = handler
 }
 
 func main() {
-	lambda.Start(getNoteHandler)
+	lambda.Start(web.LambdaHandler(getNoteHandler))
 }
@@ -0,0 +1,37 @@
+// Command rotate-keys wraps data keys of all stored notes by the master key named by KMS_KEY_ID,
+// so older master keys can be retired. Note content is never decrypted.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/provider"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Storage.Backend != config.BackendDynamoDB || cfg.KMS.Provider == config.KMSNone {
+		log.Fatalf("envelope encryption needs STORAGE_BACKEND=%s and KMS_PROVIDER set", config.BackendDynamoDB)
+	}
+
+	awsCfg, err := provider.AWSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys, err := provider.KeyManager(cfg.KMS, awsCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage := provider.DynamoStorage(awsCfg, cfg.Storage.TableName, keys)
+
+	rewrapped, err := storage.RewrapDataKeys(context.Background())
+	log.Printf("rewrapped data keys of %d notes under master key %s", rewrapped, cfg.KMS.KeyID)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/provider"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"go.uber.org/zap"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	shutdownTimeout   = 15 * time.Second
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, err := provider.Logger()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	services, err := provider.NewServices(cfg, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router := rest.NewAPI(services.Creator, services.Getter, services.Manager)
+
+	middleware, err := provider.Middleware(cfg.Web, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handler := web.HTTPHandler(middleware.WrapWithCorsAndLogging(router.Serve), nil)
+
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	if err := serve(srv, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile, logger); err != nil {
+		log.Fatal(err)
+	}
+
+	// read receipts queued by the last requests are still delivered, and the storage released, before exiting
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := services.Close(ctx); err != nil {
+		logger.Warnw("close services", "error", err)
+	}
+}
+
+// serve runs srv until SIGINT or SIGTERM and then waits for in-flight requests to finish
+func serve(srv *http.Server, certFile, keyFile string, logger *zap.SugaredLogger) error {
+	errs := make(chan error, 1)
+	go func() {
+		logger.Infow("listening", "addr", srv.Addr)
+		if certFile != "" || keyFile != "" {
+			errs <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		logger.Infow("shutting down", "signal", sig.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
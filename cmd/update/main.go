@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/platform/provider"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+var updateNoteHandler web.Handler
+
+func init() {
+	handler, err := provider.Handler(func(s *provider.Services) web.Handler {
+		return rest.UpdateNote(s.Manager)
+	})
+	if err != nil {
+		log.Fatalf("initialize update note handler: %v", err)
+	}
+	updateNoteHandler = handler
+}
+
+func main() {
+	lambda.Start(web.LambdaHandler(updateNoteHandler))
+}
@@ -0,0 +1,18 @@
+// Command webhooks delivers read receipts that API functions queued in SQS. SQS retries
+// failed deliveries and moves receipts that keep failing to a dead-letter queue.
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/projects/secure-notes/internal/platform/provider"
+)
+
+func main() {
+	logger, err := provider.Logger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	lambda.Start(provider.WebhookReceiver(logger))
+}
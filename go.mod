@@ -7,6 +7,7 @@ require (
 	github.com/kr/pretty v0.2.0 // indirect
 	github.com/speps/go-hashids v2.0.0+incompatible
 	github.com/stretchr/testify v1.5.1
+	go.etcd.io/bbolt v1.3.5
 	go.uber.org/zap v1.14.0
 	golang.org/x/crypto v0.0.0-20200221231518-2aa609cf4a9d
 )
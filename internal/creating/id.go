@@ -0,0 +1,139 @@
+package creating
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/speps/go-hashids"
+)
+
+// DefaultIDSalt is the public hashids salt of early releases. Configuration rejects it,
+// since IDs salted with it can be decoded and enumerated by anyone.
+const DefaultIDSalt = "salt for secure notes app"
+
+// DefaultIDAlphabet omits characters that are easy to confuse: 0, O, o, 1, I, l
+const DefaultIDAlphabet = "23456789abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// IDGenerator generates an ID for a new note
+type IDGenerator func(ctx context.Context) (string, error)
+
+type noteCounter interface {
+	IncrementNoteCounter(context.Context) (int, error)
+}
+
+// SequentialIDs encodes a global note counter with hashids. IDs are short, but
+// anyone who knows the salt can decode and enumerate them.
+func SequentialIDs(counter noteCounter, salt string) IDGenerator {
+	return func(ctx context.Context) (string, error) {
+		n, err := counter.IncrementNoteCounter(ctx)
+		if err != nil {
+			return "", fmt.Errorf("increment note counter: %w", err)
+		}
+		return generateHumanFriendlyID(n, salt), nil
+	}
+}
+
+// RandomIDs draws length characters uniformly from alphabet using crypto/rand
+func RandomIDs(length int, alphabet string) (IDGenerator, error) {
+	if length < 1 {
+		return nil, fmt.Errorf("random ID length must be positive, got %d", length)
+	}
+	if len(alphabet) < 2 {
+		return nil, errors.New("random ID alphabet needs at least 2 characters")
+	}
+
+	return func(ctx context.Context) (string, error) {
+		var sb strings.Builder
+		for i := 0; i < length; i++ {
+			n, err := randomIndex(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(alphabet[n])
+		}
+		return sb.String(), nil
+	}, nil
+}
+
+// WordIDs joins count words drawn uniformly from words using crypto/rand, e.g. "lamp-gold-river-fern"
+func WordIDs(count int, words []string, separator string) (IDGenerator, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("word ID count must be positive, got %d", count)
+	}
+	if len(words) < 2 {
+		return nil, errors.New("word ID list needs at least 2 words")
+	}
+
+	return func(ctx context.Context) (string, error) {
+		picked := make([]string, count)
+		for i := range picked {
+			n, err := randomIndex(len(words))
+			if err != nil {
+				return "", err
+			}
+			picked[i] = words[n]
+		}
+		return strings.Join(picked, separator), nil
+	}, nil
+}
+
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("read random index: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+func generateHumanFriendlyID(noteCounter int, salt string) string {
+	hd := hashids.NewData()
+	hd.Salt = salt
+	hd.MinLength = 5
+	h, _ := hashids.NewWithData(hd)
+	e, _ := h.Encode([]int{noteCounter})
+	return e
+}
+
+// DefaultWords is a list of short, distinct English words for WordIDs
+var DefaultWords = []string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby", "back", "ball",
+	"band", "bank", "base", "bath", "bear", "beat", "bell", "belt", "bend", "best",
+	"bird", "blow", "blue", "boat", "body", "bold", "bone", "book", "boot", "born",
+	"boss", "both", "bowl", "bulk", "burn", "bush", "busy", "cake", "call", "calm",
+	"camp", "card", "care", "cart", "case", "cash", "cast", "cell", "chat", "chip",
+	"city", "clay", "clip", "club", "coal", "coat", "code", "cold", "cook", "cool",
+	"cope", "copy", "cord", "core", "corn", "cost", "crew", "crop", "cube", "cure",
+	"dark", "data", "dawn", "deal", "dear", "deck", "deep", "deer", "desk", "dial",
+	"diet", "dish", "dock", "door", "dose", "down", "draw", "drum", "dual", "duck",
+	"dust", "duty", "earn", "ease", "east", "easy", "edge", "epic", "even", "exam",
+	"exit", "face", "fact", "fair", "fall", "farm", "fast", "fern", "file", "film",
+	"fine", "fire", "firm", "fish", "flag", "flat", "flow", "foam", "fold", "folk",
+	"font", "food", "foot", "fork", "form", "fort", "four", "free", "frog", "fuel",
+	"full", "fund", "gain", "game", "gate", "gear", "gift", "girl", "glad", "glow",
+	"goal", "gold", "golf", "good", "gram", "gray", "grid", "grow", "gulf", "hair",
+	"half", "hall", "hand", "harp", "hawk", "head", "heat", "herb", "hero", "high",
+	"hill", "hint", "hold", "home", "hood", "hook", "hope", "horn", "host", "hour",
+	"huge", "hunt", "idea", "inch", "iron", "item", "jazz", "join", "joke", "jump",
+	"jury", "keen", "keep", "kind", "king", "kite", "knee", "knot", "lake", "lamp",
+	"land", "lane", "last", "lava", "lawn", "lead", "leaf", "lean", "left", "lens",
+	"life", "lift", "lime", "line", "link", "lion", "list", "loan", "lock", "loft",
+	"long", "loop", "lord", "loud", "love", "luck", "lung", "main", "mall", "many",
+	"mark", "mask", "mast", "meal", "melt", "menu", "mild", "milk", "mind", "mine",
+	"mint", "moon", "moss", "most", "move", "much", "nail", "name", "navy", "near",
+	"neck", "nest", "news", "next", "nice", "node", "noon", "nose", "note", "oath",
+	"oval", "oven", "pack", "page", "pair", "palm", "park", "part", "path", "peak",
+	"pear", "pine", "pink", "pipe", "plan", "play", "plot", "plum", "poem", "pole",
+	"pond", "pool", "port", "pour", "pure", "quiz", "race", "rail", "rain", "ramp",
+	"rank", "rare", "rest", "rice", "ride", "ring", "road", "rock", "roof", "room",
+	"root", "rope", "rose", "ruby", "rule", "safe", "sail", "salt", "sand", "seal",
+	"seed", "ship", "shoe", "silk", "sing", "site", "skip", "slow", "snow", "soap",
+	"sock", "soft", "soil", "song", "soup", "star", "stem", "swan", "tail", "talk",
+	"tank", "tape", "team", "tent", "test", "tide", "tile", "time", "tone", "tool",
+	"tour", "town", "tree", "trip", "tube", "tune", "unit", "vase", "vast", "verb",
+	"view", "vine", "vote", "wave", "weld", "west", "wolf", "wood", "wool", "yard",
+	"year", "zero", "zone",
+}
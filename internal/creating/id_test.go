@@ -0,0 +1,62 @@
+package creating_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RandomIDs(t *testing.T) {
+	// given
+	genID, err := creating.RandomIDs(12, creating.DefaultIDAlphabet)
+	assert.NoError(t, err)
+
+	// when
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := genID(context.TODO())
+		assert.NoError(t, err)
+		seen[id] = true
+
+		// then
+		assert.Regexp(t, regexp.MustCompile(`^[2-9a-km-zA-HJ-NP-Z]{12}$`), id)
+	}
+	assert.Len(t, seen, 1000)
+}
+
+func Test_RandomIDsInvalidConfig(t *testing.T) {
+	_, err := creating.RandomIDs(0, creating.DefaultIDAlphabet)
+	assert.Error(t, err)
+
+	_, err = creating.RandomIDs(12, "a")
+	assert.Error(t, err)
+}
+
+func Test_WordIDs(t *testing.T) {
+	// given
+	genID, err := creating.WordIDs(4, creating.DefaultWords, "-")
+	assert.NoError(t, err)
+
+	// when
+	id, err := genID(context.TODO())
+
+	// then
+	assert.NoError(t, err)
+	words := strings.Split(id, "-")
+	assert.Len(t, words, 4)
+	for _, w := range words {
+		assert.Contains(t, creating.DefaultWords, w)
+	}
+}
+
+func Test_DefaultWordsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, w := range creating.DefaultWords {
+		assert.False(t, seen[w], "duplicate word %q", w)
+		seen[w] = true
+	}
+}
@@ -1,18 +1,53 @@
 package creating
 
+import "github.com/projects/secure-notes/internal/platform/security"
+
 // Note defines properties of a note to be created
 type Note struct {
 	Text            string `json:"text"`
 	Password        string `json:"password"`
 	LifeTimeSeconds int64  `json:"lifeTimeSeconds"`
-	OneTimeRead     bool   `json:"oneTimeRead"`
+
+	// MaxReads deletes the note after that many successful reads, 0 means unlimited until TTL.
+	// OneTimeRead is kept for older clients and means the same as MaxReads=1.
+	MaxReads    int  `json:"maxReads"`
+	OneTimeRead bool `json:"oneTimeRead"`
+
+	// MaxFailedAttempts destroys the note after that many wrong passwords, 0 means the service default
+	MaxFailedAttempts int `json:"maxFailedAttempts"`
+
+	// Ciphertext and Metadata replace Text and Password when the client encrypted
+	// the note itself (zero-knowledge mode). Both are stored verbatim; the key never
+	// reaches the server because clients share it in the URL fragment.
+	Ciphertext string            `json:"ciphertext"`
+	Metadata   map[string]string `json:"metadata"`
+
+	// WebhookURL receives a read receipt signed with WebhookSecret whenever the note is revealed
+	WebhookURL    string `json:"webhookUrl"`
+	WebhookSecret string `json:"webhookSecret"`
 }
 
 // SecureNote define properties of a note after securing it
 type SecureNote struct {
-	ID          string `dynamodbav:"pk"`
-	Text        string `dynamodbav:"text"`
-	Hash        string `dynamodbav:"hash"`
-	TTL         int64  `dynamodbav:"ttl"`
-	OneTimeRead bool   `dynamodbav:"oneTimeRead"`
+	ID         string              `dynamodbav:"pk"`
+	Sealed     security.SealedText `dynamodbav:"sealed"`
+	TTL        int64               `dynamodbav:"ttl"`
+	MaxReads   int                 `dynamodbav:"readsLeft"`
+	Opaque     bool                `dynamodbav:"opaque"`
+	Ciphertext string              `dynamodbav:"ciphertext"`
+	Metadata   map[string]string   `dynamodbav:"metadata"`
+
+	MaxFailedAttempts int `dynamodbav:"maxFailedAttempts"`
+
+	ManagementTokenHash string `dynamodbav:"managementTokenHash"`
+
+	WebhookURL    string `dynamodbav:"webhookUrl"`
+	WebhookSecret string `dynamodbav:"webhookSecret"`
+}
+
+// CreatedNote is returned to the creator only. ManagementToken authorizes
+// revoking and changing the note later and is never stored in plain.
+type CreatedNote struct {
+	ID              string `json:"id"`
+	ManagementToken string `json:"managementToken"`
 }
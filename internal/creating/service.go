@@ -2,65 +2,170 @@ package creating
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
-	"github.com/speps/go-hashids"
+	"github.com/projects/secure-notes/internal/platform/logging"
+	"github.com/projects/secure-notes/internal/platform/security"
+)
+
+// maxIDAttempts bounds retries when a generated ID is already taken
+const maxIDAttempts = 5
+
+var (
+	// ErrMixedModes is used when a note carries both server-side (text, password) and client-side (ciphertext) content.
+	ErrMixedModes = errors.New("note cannot have both ciphertext and text or password")
+
+	// ErrIDTaken is returned by repository when a note with the same ID already exists.
+	ErrIDTaken = errors.New("note ID already taken")
+
+	// ErrInvalidMaxReads is used when a read budget is negative or contradicts oneTimeRead.
+	ErrInvalidMaxReads = errors.New("maxReads must not be negative nor conflict with oneTimeRead")
+
+	// ErrInvalidWebhook is used when a webhook URL is not absolute http(s) or comes without a secret.
+	ErrInvalidWebhook = errors.New("webhookUrl must be an absolute http(s) URL with a webhookSecret")
 )
 
 // Service provides note creating operation
 type Service struct {
-	repo            repository
-	now             func() time.Time
-	genHashWithSalt func(password string) (string, error)
+	repo      repository
+	now       func() time.Time
+	seal      func(text, password string) (security.SealedText, error)
+	genID     IDGenerator
+	genToken  func() (string, error)
+	hashToken func(token string) string
+	limits    Limits
 }
 
 type repository interface {
+	// CreateNote must fail with ErrIDTaken instead of overwriting an existing note
 	CreateNote(context.Context, SecureNote) error
-	IncrementNoteCounter(context.Context) (int, error)
 }
 
 // NewService provides creating note service
-func NewService(r repository, now func() time.Time, genHashWithSalt func(password string) (string, error)) *Service {
-	return &Service{repo: r, now: now, genHashWithSalt: genHashWithSalt}
+func NewService(
+	r repository,
+	now func() time.Time,
+	seal func(text, password string) (security.SealedText, error),
+	genID IDGenerator,
+	genToken func() (string, error),
+	hashToken func(token string) string,
+	limits Limits,
+) *Service {
+	return &Service{
+		repo:      r,
+		now:       now,
+		seal:      seal,
+		genID:     genID,
+		genToken:  genToken,
+		hashToken: hashToken,
+		limits:    limits,
+	}
 }
 
-// CreateNote creates secure note in storage
-func (s *Service) CreateNote(ctx context.Context, plain Note) (noteID string, err error) {
-	noteTTL := s.now().Add(time.Duration(plain.LifeTimeSeconds) * time.Second).Unix()
+// CreateNote creates secure note in storage.
+// Invalid notes are rejected with *ValidationError listing all offending fields.
+func (s *Service) CreateNote(ctx context.Context, plain Note) (CreatedNote, error) {
+	if err := s.limits.validate(plain); err != nil {
+		return CreatedNote{}, err
+	}
 
-	saltedHash, err := s.genHashWithSalt(plain.Password)
+	securedNote, err := s.secure(plain)
 	if err != nil {
-		return "", fmt.Errorf("generate hash with salt: %w", err)
+		return CreatedNote{}, err
 	}
 
-	counter, err := s.repo.IncrementNoteCounter(ctx)
+	token, err := s.genToken()
 	if err != nil {
-		return "", fmt.Errorf("increment note counter: %w", err)
+		return CreatedNote{}, fmt.Errorf("generate management token: %w", err)
 	}
+	securedNote.ManagementTokenHash = s.hashToken(token)
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		securedNote.ID, err = s.genID(ctx)
+		if err != nil {
+			return CreatedNote{}, fmt.Errorf("generate note ID: %w", err)
+		}
+
+		err = s.repo.CreateNote(ctx, securedNote)
+		if errors.Is(err, ErrIDTaken) {
+			logging.FromContext(ctx).Warnw("generated note ID already taken", "attempt", attempt+1)
+			continue
+		}
+		if err != nil {
+			return CreatedNote{}, fmt.Errorf("repository create secured note: %w", err)
+		}
+
+		logging.FromContext(ctx).Infow("note created",
+			"noteId", securedNote.ID,
+			"opaque", securedNote.Opaque,
+			"maxReads", securedNote.MaxReads,
+			"ttl", securedNote.TTL,
+		)
+		return CreatedNote{ID: securedNote.ID, ManagementToken: token}, nil
+	}
+
+	return CreatedNote{}, fmt.Errorf("repository create secured note: %w", ErrIDTaken)
+}
 
-	id := generateHumanFriendlyID(counter)
+// secure seals or stores as is content of a validated note
+func (s *Service) secure(plain Note) (SecureNote, error) {
+	maxReads, err := readBudget(plain)
+	if err != nil {
+		return SecureNote{}, err
+	}
 
 	securedNote := SecureNote{
-		ID:          id,
-		Text:        plain.Text,
-		Hash:        saltedHash,
-		TTL:         noteTTL,
-		OneTimeRead: plain.OneTimeRead,
+		TTL:      s.now().Add(time.Duration(plain.LifeTimeSeconds) * time.Second).Unix(),
+		MaxReads: maxReads,
+
+		MaxFailedAttempts: plain.MaxFailedAttempts,
+
+		WebhookURL:    plain.WebhookURL,
+		WebhookSecret: plain.WebhookSecret,
 	}
 
-	if err := s.repo.CreateNote(ctx, securedNote); err != nil {
-		return "", fmt.Errorf("repository create secured note: %w", err)
+	if plain.Ciphertext != "" {
+		securedNote.Opaque = true
+		securedNote.Ciphertext = plain.Ciphertext
+		securedNote.Metadata = plain.Metadata
+		return securedNote, nil
+	}
+
+	// no password hash is stored: a cheaper verifier next to the sealed text would let a leaked
+	// table be cracked without paying for the key derivation, so reading a note opens it instead
+	sealed, err := s.seal(plain.Text, plain.Password)
+	if err != nil {
+		return SecureNote{}, fmt.Errorf("seal note text: %w", err)
 	}
 
-	return securedNote.ID, nil
+	securedNote.Sealed = sealed
+	return securedNote, nil
 }
 
-func generateHumanFriendlyID(noteCounter int) string {
-	hd := hashids.NewData()
-	hd.Salt = "salt for secure notes app"
-	hd.MinLength = 5
-	h, _ := hashids.NewWithData(hd)
-	e, _ := h.Encode([]int{noteCounter})
-	return e
+// readBudget resolves how many times a note can be read, where oneTimeRead is a shorthand for a single read
+func readBudget(plain Note) (int, error) {
+	if plain.MaxReads < 0 {
+		return 0, ErrInvalidMaxReads
+	}
+	if !plain.OneTimeRead {
+		return plain.MaxReads, nil
+	}
+	if plain.MaxReads > 1 {
+		return 0, ErrInvalidMaxReads
+	}
+	return 1, nil
+}
+
+func validWebhook(plain Note) bool {
+	if plain.WebhookURL == "" {
+		return plain.WebhookSecret == ""
+	}
+	u, err := url.Parse(plain.WebhookURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+	return plain.WebhookSecret != ""
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/security"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -23,29 +24,25 @@ func TestService_CreateNoteOK(t *testing.T) {
 	repository := mockRepository{}
 	repository.On("IncrementNoteCounter").Return(1, nil)
 	repository.On("CreateNote", creating.SecureNote{
-		ID:          "qx2rx",
-		Text:        "Hello World",
-		Hash:        "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC",
-		TTL:         time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
-		OneTimeRead: true,
+		ID:                  "qx2rx",
+		Sealed:              sealedHelloWorld,
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxReads:            1,
+		ManagementTokenHash: "hashed:mgmt-token",
 	}).Return(nil)
 
 	timer := func() time.Time {
 		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
 	}
 
-	hashGen := func(pwd string) (string, error) {
-		return "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC", nil
-	}
-
 	// when
-	s := creating.NewService(&repository, timer, hashGen)
+	s := creating.NewService(&repository, timer, sealGen, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
 
 	// then
-	gotNoteID, gotErr := s.CreateNote(context.TODO(), createNote)
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
 
 	assert.NoError(t, gotErr)
-	assert.Equal(t, "qx2rx", gotNoteID)
+	assert.Equal(t, creating.CreatedNote{ID: "qx2rx", ManagementToken: "mgmt-token"}, gotNote)
 }
 
 func TestService_CreateNoteDatabaseError(t *testing.T) {
@@ -60,30 +57,312 @@ func TestService_CreateNoteDatabaseError(t *testing.T) {
 	repository := mockRepository{}
 	repository.On("IncrementNoteCounter").Return(1, nil)
 	repository.On("CreateNote", creating.SecureNote{
-		ID:          "qx2rx",
-		Text:        "Hello World",
-		Hash:        "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC",
-		TTL:         time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
-		OneTimeRead: true,
+		ID:                  "qx2rx",
+		Sealed:              sealedHelloWorld,
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxReads:            1,
+		ManagementTokenHash: "hashed:mgmt-token",
 	}).Return(errors.New("some error from database"))
 
 	timer := func() time.Time {
 		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
 	}
 
-	hashGen := func(pwd string) (string, error) {
-		return "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC", nil
+	s := creating.NewService(&repository, timer, sealGen, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
+
+	// when
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
+
+	// then
+
+	assert.EqualError(t, gotErr, "repository create secured note: some error from database")
+	assert.Equal(t, creating.CreatedNote{}, gotNote)
+}
+
+func TestService_CreateNoteSealError(t *testing.T) {
+	// given
+	createNote := creating.Note{
+		Text:            "Hello World",
+		Password:        "abc",
+		LifeTimeSeconds: 3600,
 	}
 
-	s := creating.NewService(&repository, timer, hashGen)
+	repository := mockRepository{}
+
+	timer := func() time.Time {
+		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	}
+
+	failingSeal := func(text, pwd string) (security.SealedText, error) {
+		return security.SealedText{}, errors.New("no entropy")
+	}
+
+	s := creating.NewService(&repository, timer, failingSeal, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
 
 	// when
-	gotNoteID, gotErr := s.CreateNote(context.TODO(), createNote)
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
 
 	// then
+	assert.EqualError(t, gotErr, "seal note text: no entropy")
+	assert.Equal(t, creating.CreatedNote{}, gotNote)
+	repository.AssertNotCalled(t, "CreateNote", mock.Anything)
+}
 
-	assert.EqualError(t, gotErr, "repository create secured note: some error from database")
-	assert.Equal(t, "", gotNoteID)
+func TestService_CreateOpaqueNoteOK(t *testing.T) {
+	// given
+	createNote := creating.Note{
+		Ciphertext:      "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:        map[string]string{"alg": "AES-GCM", "iv": "aXYxMjM0NTY3ODkw"},
+		LifeTimeSeconds: 3600,
+		OneTimeRead:     true,
+	}
+
+	repository := mockRepository{}
+	repository.On("IncrementNoteCounter").Return(1, nil)
+	repository.On("CreateNote", creating.SecureNote{
+		ID:                  "qx2rx",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxReads:            1,
+		Opaque:              true,
+		Ciphertext:          "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:            map[string]string{"alg": "AES-GCM", "iv": "aXYxMjM0NTY3ODkw"},
+		ManagementTokenHash: "hashed:mgmt-token",
+	}).Return(nil)
+
+	timer := func() time.Time {
+		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	}
+
+	failingSeal := func(text, pwd string) (security.SealedText, error) {
+		return security.SealedText{}, errors.New("must not seal opaque note")
+	}
+
+	s := creating.NewService(&repository, timer, failingSeal, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
+
+	// when
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, creating.CreatedNote{ID: "qx2rx", ManagementToken: "mgmt-token"}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_CreateNoteMixedModes(t *testing.T) {
+	// given
+	createNote := creating.Note{
+		Text:            "Hello World",
+		Password:        "abc",
+		Ciphertext:      "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		LifeTimeSeconds: 3600,
+	}
+
+	repository := mockRepository{}
+
+	timer := func() time.Time {
+		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	}
+
+	s := creating.NewService(&repository, timer, sealGen, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
+
+	// when
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
+
+	// then
+	assert.True(t, errors.Is(gotErr, creating.ErrMixedModes), "got %v", gotErr)
+	assert.Equal(t, creating.CreatedNote{}, gotNote)
+	repository.AssertNotCalled(t, "IncrementNoteCounter")
+}
+
+func TestService_CreateNoteReadBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxReads     int
+		oneTimeRead  bool
+		wantMaxReads int
+		wantErr      error
+	}{
+		{name: "unlimited", wantMaxReads: 0},
+		{name: "max reads", maxReads: 3, wantMaxReads: 3},
+		{name: "one time read", oneTimeRead: true, wantMaxReads: 1},
+		{name: "one time read with one max read", oneTimeRead: true, maxReads: 1, wantMaxReads: 1},
+		{name: "one time read conflicts with max reads", oneTimeRead: true, maxReads: 3, wantErr: creating.ErrInvalidMaxReads},
+		{name: "negative max reads", maxReads: -1, wantErr: creating.ErrInvalidMaxReads},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			createNote := creating.Note{
+				Ciphertext:      "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+				LifeTimeSeconds: 3600,
+				MaxReads:        tt.maxReads,
+				OneTimeRead:     tt.oneTimeRead,
+			}
+
+			repository := mockRepository{}
+			repository.On("IncrementNoteCounter").Return(1, nil)
+			repository.On("CreateNote", creating.SecureNote{
+				ID:                  "qx2rx",
+				TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+				MaxReads:            tt.wantMaxReads,
+				Opaque:              true,
+				Ciphertext:          "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+				ManagementTokenHash: "hashed:mgmt-token",
+			}).Return(nil)
+
+			timer := func() time.Time {
+				return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+			}
+
+			s := creating.NewService(&repository, timer, nil, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
+
+			// when
+			_, gotErr := s.CreateNote(context.TODO(), createNote)
+
+			// then
+			assert.True(t, errors.Is(gotErr, tt.wantErr), "got %v", gotErr)
+		})
+	}
+}
+
+func TestService_CreateNoteWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		secret  string
+		wantErr error
+	}{
+		{name: "without webhook"},
+		{name: "https webhook", url: "https://hooks.example.com/read", secret: "whsec"},
+		{name: "http webhook", url: "http://hooks.example.com/read", secret: "whsec"},
+		{name: "webhook without secret", url: "https://hooks.example.com/read", wantErr: creating.ErrInvalidWebhook},
+		{name: "secret without webhook", secret: "whsec", wantErr: creating.ErrInvalidWebhook},
+		{name: "relative webhook", url: "/read", secret: "whsec", wantErr: creating.ErrInvalidWebhook},
+		{name: "non http webhook", url: "file:///etc/passwd", secret: "whsec", wantErr: creating.ErrInvalidWebhook},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			createNote := creating.Note{
+				Ciphertext:      "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+				LifeTimeSeconds: 3600,
+				WebhookURL:      tt.url,
+				WebhookSecret:   tt.secret,
+			}
+
+			repository := mockRepository{}
+			repository.On("IncrementNoteCounter").Return(1, nil)
+			repository.On("CreateNote", creating.SecureNote{
+				ID:                  "qx2rx",
+				TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+				Opaque:              true,
+				Ciphertext:          "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+				ManagementTokenHash: "hashed:mgmt-token",
+				WebhookURL:          tt.url,
+				WebhookSecret:       tt.secret,
+			}).Return(nil)
+
+			timer := func() time.Time {
+				return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+			}
+
+			s := creating.NewService(&repository, timer, nil, creating.SequentialIDs(&repository, creating.DefaultIDSalt), tokenGen, hashToken, creating.DefaultLimits)
+
+			// when
+			_, gotErr := s.CreateNote(context.TODO(), createNote)
+
+			// then
+			assert.True(t, errors.Is(gotErr, tt.wantErr), "got %v", gotErr)
+		})
+	}
+}
+
+func TestService_CreateNoteRetriesTakenID(t *testing.T) {
+	// given
+	createNote := creating.Note{
+		Text:            "Hello World",
+		Password:        "abc",
+		LifeTimeSeconds: 3600,
+	}
+
+	repository := mockRepository{}
+	repository.On("CreateNote", mock.MatchedBy(func(sn creating.SecureNote) bool { return sn.ID == "taken" })).
+		Return(creating.ErrIDTaken)
+	repository.On("CreateNote", mock.MatchedBy(func(sn creating.SecureNote) bool { return sn.ID == "free" })).
+		Return(nil)
+
+	timer := func() time.Time {
+		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	}
+
+	ids := []string{"taken", "taken", "free"}
+	genID := func(ctx context.Context) (string, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	}
+
+	s := creating.NewService(&repository, timer, sealGen, genID, tokenGen, hashToken, creating.DefaultLimits)
+
+	// when
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "free", gotNote.ID)
+	repository.AssertNumberOfCalls(t, "CreateNote", 3)
+}
+
+func TestService_CreateNoteGivesUpOnTakenIDs(t *testing.T) {
+	// given
+	createNote := creating.Note{
+		Text:            "Hello World",
+		Password:        "abc",
+		LifeTimeSeconds: 3600,
+	}
+
+	repository := mockRepository{}
+	repository.On("CreateNote", mock.Anything).Return(creating.ErrIDTaken)
+
+	timer := func() time.Time {
+		return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	}
+
+	genID := func(ctx context.Context) (string, error) {
+		return "taken", nil
+	}
+
+	s := creating.NewService(&repository, timer, sealGen, genID, tokenGen, hashToken, creating.DefaultLimits)
+
+	// when
+	gotNote, gotErr := s.CreateNote(context.TODO(), createNote)
+
+	// then
+	assert.True(t, errors.Is(gotErr, creating.ErrIDTaken))
+	assert.Equal(t, creating.CreatedNote{}, gotNote)
+}
+
+var sealedHelloWorld = security.SealedText{
+	Cipher:     security.CipherAES256GCM,
+	KDF:        security.KDFArgon2id,
+	KDFParams:  security.DefaultKDFParams,
+	Salt:       []byte("0123456789abcdef"),
+	Nonce:      []byte("0123456789ab"),
+	Ciphertext: []byte("sealed Hello World"),
+}
+
+func sealGen(text, pwd string) (security.SealedText, error) {
+	return sealedHelloWorld, nil
+}
+
+func tokenGen() (string, error) {
+	return "mgmt-token", nil
+}
+
+func hashToken(token string) string {
+	return "hashed:" + token
 }
 
 type mockRepository struct {
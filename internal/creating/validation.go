@@ -0,0 +1,150 @@
+package creating
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxPasswordBytes is the longest password accepted
+const MaxPasswordBytes = 72
+
+// Limits bound notes accepted by the service
+type Limits struct {
+	MaxTextBytes       int
+	MaxCiphertextBytes int
+	// MaxMetadataBytes bounds total length of metadata keys and values
+	MaxMetadataBytes   int
+	MinLifeTimeSeconds int64
+	MaxLifeTimeSeconds int64
+}
+
+// DefaultLimits are used unless deployment configures its own
+var DefaultLimits = Limits{
+	MaxTextBytes:       64 << 10,
+	MaxCiphertextBytes: 128 << 10,
+	MaxMetadataBytes:   4 << 10,
+	MinLifeTimeSeconds: 1,
+	MaxLifeTimeSeconds: 30 * 24 * 60 * 60,
+}
+
+var (
+	// ErrTextRequired is used when a note has neither text nor ciphertext.
+	ErrTextRequired = errors.New("text is required")
+
+	// ErrTextTooLong is used when note text exceeds Limits.MaxTextBytes.
+	ErrTextTooLong = errors.New("text is too long")
+
+	// ErrPasswordRequired is used when a note with text has no password.
+	ErrPasswordRequired = errors.New("password is required")
+
+	// ErrPasswordTooLong is used when a password exceeds MaxPasswordBytes.
+	ErrPasswordTooLong = errors.New("password is too long")
+
+	// ErrCiphertextTooLong is used when ciphertext exceeds Limits.MaxCiphertextBytes.
+	ErrCiphertextTooLong = errors.New("ciphertext is too long")
+
+	// ErrMetadataTooLong is used when metadata exceeds Limits.MaxMetadataBytes.
+	ErrMetadataTooLong = errors.New("metadata is too long")
+
+	// ErrLifetimeOutOfRange is used when lifeTimeSeconds is outside of configured limits.
+	ErrLifetimeOutOfRange = errors.New("lifeTimeSeconds is out of range")
+
+	// ErrInvalidMaxFailedAttempts is used when the limit of failed attempts is negative.
+	ErrInvalidMaxFailedAttempts = errors.New("maxFailedAttempts must not be negative")
+)
+
+// FieldError tells which field of a note is invalid and why
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid field of a note
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return "invalid note: " + strings.Join(msgs, "; ")
+}
+
+// Is matches errors of all invalid fields, so errors.Is(err, ErrTextTooLong) works on the whole list
+func (e *ValidationError) Is(target error) bool {
+	for _, f := range e.Fields {
+		if errors.Is(f.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a note against limits and returns *ValidationError listing all invalid fields
+func (l Limits) validate(plain Note) error {
+	var fields []FieldError
+	invalid := func(field string, err error) {
+		fields = append(fields, FieldError{Field: field, Err: err})
+	}
+
+	if plain.Ciphertext != "" {
+		if plain.Text != "" || plain.Password != "" {
+			invalid("ciphertext", ErrMixedModes)
+		}
+		if len(plain.Ciphertext) > l.MaxCiphertextBytes {
+			invalid("ciphertext", ErrCiphertextTooLong)
+		}
+		if metadataBytes(plain.Metadata) > l.MaxMetadataBytes {
+			invalid("metadata", ErrMetadataTooLong)
+		}
+	} else {
+		switch {
+		case plain.Text == "":
+			invalid("text", ErrTextRequired)
+		case len(plain.Text) > l.MaxTextBytes:
+			invalid("text", ErrTextTooLong)
+		}
+		switch {
+		case plain.Password == "":
+			invalid("password", ErrPasswordRequired)
+		case len(plain.Password) > MaxPasswordBytes:
+			invalid("password", ErrPasswordTooLong)
+		}
+	}
+
+	if plain.LifeTimeSeconds < l.MinLifeTimeSeconds || plain.LifeTimeSeconds > l.MaxLifeTimeSeconds {
+		invalid("lifeTimeSeconds", ErrLifetimeOutOfRange)
+	}
+	if _, err := readBudget(plain); err != nil {
+		invalid("maxReads", err)
+	}
+	if plain.MaxFailedAttempts < 0 {
+		invalid("maxFailedAttempts", ErrInvalidMaxFailedAttempts)
+	}
+	if !validWebhook(plain) {
+		invalid("webhookUrl", ErrInvalidWebhook)
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func metadataBytes(metadata map[string]string) int {
+	n := 0
+	for k, v := range metadata {
+		n += len(k) + len(v)
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package creating_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_CreateNoteValidation(t *testing.T) {
+	limits := creating.Limits{
+		MaxTextBytes:       5,
+		MaxCiphertextBytes: 8,
+		MaxMetadataBytes:   4,
+		MinLifeTimeSeconds: 60,
+		MaxLifeTimeSeconds: 3600,
+	}
+
+	tests := []struct {
+		name       string
+		note       creating.Note
+		wantFields []creating.FieldError
+	}{
+		{
+			name: "empty note",
+			note: creating.Note{},
+			wantFields: []creating.FieldError{
+				{Field: "text", Err: creating.ErrTextRequired},
+				{Field: "password", Err: creating.ErrPasswordRequired},
+				{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+			},
+		},
+		{
+			name: "text and password too long",
+			note: creating.Note{Text: "Hello World", Password: strings.Repeat("p", creating.MaxPasswordBytes+1), LifeTimeSeconds: 60},
+			wantFields: []creating.FieldError{
+				{Field: "text", Err: creating.ErrTextTooLong},
+				{Field: "password", Err: creating.ErrPasswordTooLong},
+			},
+		},
+		{
+			name: "lifetime too long",
+			note: creating.Note{Text: "Hello", Password: "abc", LifeTimeSeconds: 3601},
+			wantFields: []creating.FieldError{
+				{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+			},
+		},
+		{
+			name: "negative lifetime",
+			note: creating.Note{Text: "Hello", Password: "abc", LifeTimeSeconds: -1},
+			wantFields: []creating.FieldError{
+				{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+			},
+		},
+		{
+			name: "ciphertext and metadata too long",
+			note: creating.Note{Ciphertext: "U2FsdGVkX1+v", Metadata: map[string]string{"alg": "AES-GCM"}, LifeTimeSeconds: 60},
+			wantFields: []creating.FieldError{
+				{Field: "ciphertext", Err: creating.ErrCiphertextTooLong},
+				{Field: "metadata", Err: creating.ErrMetadataTooLong},
+			},
+		},
+		{
+			name: "negative counters",
+			note: creating.Note{Text: "Hello", Password: "abc", LifeTimeSeconds: 60, MaxReads: -1, MaxFailedAttempts: -1},
+			wantFields: []creating.FieldError{
+				{Field: "maxReads", Err: creating.ErrInvalidMaxReads},
+				{Field: "maxFailedAttempts", Err: creating.ErrInvalidMaxFailedAttempts},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			repository := mockRepository{}
+			timer := func() time.Time {
+				return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+			}
+
+			s := creating.NewService(&repository, timer, nil, nil, tokenGen, hashToken, limits)
+
+			// when
+			_, gotErr := s.CreateNote(context.TODO(), tt.note)
+
+			// then
+			var validationErr *creating.ValidationError
+			if assert.True(t, errors.As(gotErr, &validationErr), "got %v", gotErr) {
+				assert.Equal(t, tt.wantFields, validationErr.Fields)
+			}
+			for _, f := range tt.wantFields {
+				assert.True(t, errors.Is(gotErr, f.Err))
+			}
+			repository.AssertNotCalled(t, "CreateNote")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package getting
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMaxFailedAttempts applies to notes whose creator did not choose a limit
+	DefaultMaxFailedAttempts = 10
+
+	// After n failed attempts the next guess is accepted only after baseBackoff * 2^(n-1), at most maxBackoff
+	baseBackoff = time.Second
+	maxBackoff  = 15 * time.Minute
+)
+
+var (
+	// ErrTooManyAttempts is used when a password guess comes before the backoff after previous failures elapsed.
+	ErrTooManyAttempts = errors.New("too many failed attempts")
+
+	// ErrAttemptRejected is used by repositories when a note does not take another guess, because
+	// guesses reserved since it was read reached its limit or restarted its backoff.
+	ErrAttemptRejected = errors.New("attempt rejected")
+)
+
+// Attempt is a password guess counted as failed before the password is checked, so concurrent
+// guesses cannot all pass the limit and backoff checked against the note they read.
+type Attempt struct {
+	// Limit of failed attempts of the note
+	Limit int
+	// LastFailedBy is the latest time of the last failure, in unix seconds, whose backoff elapsed
+	LastFailedBy int64
+	// At is when the guess was made
+	At time.Time
+}
+
+// Accepts tells whether a note with failedAttempts, the last one at lastFailedAt, takes the guess
+func (a Attempt) Accepts(failedAttempts int, lastFailedAt int64) bool {
+	return failedAttempts < a.Limit && lastFailedAt <= a.LastFailedBy
+}
+
+// RetryAfterError tells when the next password guess will be accepted
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+func backoff(failedAttempts int) time.Duration {
+	if failedAttempts < 1 {
+		return 0
+	}
+	d := baseBackoff
+	for i := 1; i < failedAttempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+func maxFailedAttempts(n SecureNote) int {
+	if n.MaxFailedAttempts > 0 {
+		return n.MaxFailedAttempts
+	}
+	return DefaultMaxFailedAttempts
+}
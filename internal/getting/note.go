@@ -1,17 +1,60 @@
 package getting
 
-// SecureNote defines properties of retrieved note from database
+import "github.com/projects/secure-notes/internal/platform/security"
+
+// SecureNote defines properties of retrieved note from database.
+// ReadsLeft is 0 for notes that can be read until they expire.
 type SecureNote struct {
-	ID          string `dynamodbav:"pk"`
-	Text        string `dynamodbav:"text"`
-	Hash        string `dynamodbav:"hash"`
-	TTL         int64  `dynamodbav:"ttl"`
-	OneTimeRead bool   `dynamodbav:"oneTimeRead"`
+	ID         string              `dynamodbav:"pk"`
+	Sealed     security.SealedText `dynamodbav:"sealed"`
+	TTL        int64               `dynamodbav:"ttl"`
+	ReadsLeft  int                 `dynamodbav:"readsLeft"`
+	Opaque     bool                `dynamodbav:"opaque"`
+	Ciphertext string              `dynamodbav:"ciphertext"`
+	Metadata   map[string]string   `dynamodbav:"metadata"`
+
+	MaxFailedAttempts int   `dynamodbav:"maxFailedAttempts"`
+	FailedAttempts    int   `dynamodbav:"failedAttempts"`
+	LastFailedAt      int64 `dynamodbav:"lastFailedAt"`
+
+	WebhookURL    string `dynamodbav:"webhookUrl"`
+	WebhookSecret string `dynamodbav:"webhookSecret"`
 }
 
-// Note define properties of successfully decrypted note
+// Note define properties of successfully decrypted note.
+// Opaque notes carry client-encrypted Ciphertext and Metadata instead of Text.
 type Note struct {
-	ID   string `json:"id"`
-	Text string `json:"text"`
-	TTL  int64  `json:"ttl"`
+	ID         string            `json:"id"`
+	Text       string            `json:"text"`
+	TTL        int64             `json:"ttl"`
+	Ciphertext string            `json:"ciphertext,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	// ReadsLeft is nil for notes without a read budget
+	ReadsLeft *int `json:"readsLeft,omitempty"`
+}
+
+// SecureNoteMeta defines properties of a note that can be loaded without its text
+type SecureNoteMeta struct {
+	ID        string `dynamodbav:"pk"`
+	TTL       int64  `dynamodbav:"ttl"`
+	ReadsLeft int    `dynamodbav:"readsLeft"`
+	Opaque    bool   `dynamodbav:"opaque"`
+}
+
+// NoteMeta describes a note without revealing or consuming it
+type NoteMeta struct {
+	ID               string `json:"id"`
+	ExpiresAt        int64  `json:"expiresAt"`
+	ReadsLeft        *int   `json:"readsLeft,omitempty"`
+	PasswordRequired bool   `json:"passwordRequired"`
+}
+
+// ReadReceipt is sent to the webhook of a note after it was revealed. It never carries note content.
+type ReadReceipt struct {
+	Event  string `json:"event"`
+	NoteID string `json:"noteId"`
+	ReadAt int64  `json:"readAt"`
+	// ReadsLeft is nil for notes without a read budget
+	ReadsLeft *int `json:"readsLeft,omitempty"`
+	Destroyed bool `json:"destroyed"`
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/projects/secure-notes/internal/platform/logging"
+	"github.com/projects/secure-notes/internal/platform/security"
 )
 
 var (
@@ -14,19 +16,54 @@ var (
 
 	// ErrNotAuthorized
 	ErrNotAuthorized = errors.New("wrong password")
+
+	// ErrExpired is used when a note outlived its TTL but was not yet swept by storage.
+	// Callers should not be able to tell it apart from ErrNotFound.
+	ErrExpired = errors.New("note expired")
 )
 
+// ReadEvent names read receipts sent to note webhooks
+const ReadEvent = "note.read"
+
 type Service struct {
 	repo repository
+	now  func() time.Time
+	// open fails with security.ErrDecrypt for wrong passwords, so it also verifies them
+	open func(sealed security.SealedText, password string) (string, error)
+	out  outbox
+}
+
+// outbox delivers webhook events in the background, so enqueueing must not block on the receiver
+type outbox interface {
+	Enqueue(url, secret string, event interface{}) error
 }
 
 type repository interface {
 	GetNote(ctx context.Context, noteID string) (SecureNote, error)
+	// GetNoteMeta must not load note content
+	GetNoteMeta(ctx context.Context, noteID string) (SecureNoteMeta, error)
+	// ConsumeRead atomically takes one read from the budget of a note, records it at the given
+	// time and returns the note with its remaining reads. The last read removes note content,
+	// so of concurrent callers racing for it exactly one succeeds and the others get ErrNotFound.
+	ConsumeRead(ctx context.Context, noteID string, at time.Time) (SecureNote, error)
+	// RecordRead records a read of a note without a read budget
+	RecordRead(ctx context.Context, noteID string, at time.Time) error
 	DeleteNote(ctx context.Context, noteID string) error
+	// ReserveAttempt atomically increments failed attempts of a note, stores a.At as the time of
+	// the last failure and returns the incremented number of attempts, if the note accepts the
+	// attempt. Otherwise it fails with ErrAttemptRejected.
+	ReserveAttempt(ctx context.Context, noteID string, a Attempt) (failedAttempts int, err error)
+	// ClearFailedAttempts resets failed attempts of a note whose password was verified
+	ClearFailedAttempts(ctx context.Context, noteID string) error
 }
 
-func NewService(repository repository) *Service {
-	return &Service{repo: repository}
+func NewService(
+	repository repository,
+	now func() time.Time,
+	open func(sealed security.SealedText, password string) (string, error),
+	out outbox,
+) *Service {
+	return &Service{repo: repository, now: now, open: open, out: out}
 }
 
 func (s *Service) GetNote(ctx context.Context, noteID, password string) (Note, error) {
@@ -35,31 +72,176 @@ func (s *Service) GetNote(ctx context.Context, noteID, password string) (Note, e
 		return Note{}, fmt.Errorf("repository get note: %w", err)
 	}
 
-	ok := verifyPassword(secureNote.Hash, password)
-	if !ok {
-		return Note{}, ErrNotAuthorized
+	// DynamoDB removes expired items lazily, so TTL has to be enforced on read as well
+	if s.expired(secureNote.TTL) {
+		// best effort: the note stays unreadable even if the delete fails
+		if err := s.repo.DeleteNote(ctx, secureNote.ID); err != nil {
+			logging.FromContext(ctx).Warnw("delete expired note", "noteId", secureNote.ID, "error", err)
+		}
+		return Note{}, ErrExpired
 	}
 
-	if secureNote.OneTimeRead {
-		if err := s.repo.DeleteNote(ctx, secureNote.ID); err != nil {
-			return Note{}, fmt.Errorf("delete note: %w", err)
+	// the note is opened before a read is taken, so a note that cannot be opened keeps its budget
+	var text string
+	if !secureNote.Opaque {
+		text, err = s.unlock(ctx, secureNote, password)
+		if err != nil {
+			return Note{}, err
+		}
+	}
+	note := reveal(secureNote, text)
+
+	readAt := s.now()
+	var readsLeft *int
+	if secureNote.ReadsLeft > 0 {
+		consumed, err := s.repo.ConsumeRead(ctx, secureNote.ID, readAt)
+		if err != nil {
+			return Note{}, fmt.Errorf("repository consume read: %w", err)
+		}
+		secureNote.ReadsLeft = consumed.ReadsLeft
+		readsLeft = &secureNote.ReadsLeft
+	} else if err := s.repo.RecordRead(ctx, secureNote.ID, readAt); err != nil {
+		return Note{}, fmt.Errorf("repository record read: %w", err)
+	}
+	note.ReadsLeft = readsLeft
+
+	logging.FromContext(ctx).Infow("note read", "noteId", secureNote.ID, "destroyed", destroyed(readsLeft))
+	s.sendReadReceipt(ctx, secureNote, readAt, readsLeft)
+
+	return note, nil
+}
+
+// sendReadReceipt notifies the note webhook, if any. Delivery is best effort
+// and must never fail or delay the read itself.
+func (s *Service) sendReadReceipt(ctx context.Context, secureNote SecureNote, readAt time.Time, readsLeft *int) {
+	if secureNote.WebhookURL == "" || s.out == nil {
+		return
+	}
+
+	err := s.out.Enqueue(secureNote.WebhookURL, secureNote.WebhookSecret, ReadReceipt{
+		Event:     ReadEvent,
+		NoteID:    secureNote.ID,
+		ReadAt:    readAt.Unix(),
+		ReadsLeft: readsLeft,
+		Destroyed: destroyed(readsLeft),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Warnw("enqueue read receipt", "noteId", secureNote.ID, "error", err)
+	}
+}
+
+// destroyed tells whether a read took the last read of a note
+func destroyed(readsLeft *int) bool {
+	return readsLeft != nil && *readsLeft == 0
+}
+
+// GetNoteMeta describes a note without requiring the password and without consuming one-time notes,
+// so link previews cannot burn a secret before its recipient opens it.
+func (s *Service) GetNoteMeta(ctx context.Context, noteID string) (NoteMeta, error) {
+	meta, err := s.repo.GetNoteMeta(ctx, noteID)
+	if err != nil {
+		return NoteMeta{}, fmt.Errorf("repository get note meta: %w", err)
+	}
+
+	if s.expired(meta.TTL) {
+		return NoteMeta{}, ErrExpired
+	}
+
+	return NoteMeta{
+		ID:               meta.ID,
+		ExpiresAt:        meta.TTL,
+		ReadsLeft:        readBudget(meta.ReadsLeft),
+		PasswordRequired: !meta.Opaque,
+	}, nil
+}
+
+// reveal returns note content readable by the caller, with text opened by unlock. Opaque notes
+// are returned as stored, since only the client holds the key to decrypt them.
+func reveal(secureNote SecureNote, text string) Note {
+	if secureNote.Opaque {
+		return Note{
+			ID:         secureNote.ID,
+			TTL:        secureNote.TTL,
+			Ciphertext: secureNote.Ciphertext,
+			Metadata:   secureNote.Metadata,
 		}
 	}
 
 	return Note{
 		ID:   secureNote.ID,
-		Text: secureNote.Text,
+		Text: text,
 		TTL:  secureNote.TTL,
-	}, nil
+	}
 }
 
-func verifyPassword(hashedPwd, plainPwd string) bool {
-	byteHash := []byte(hashedPwd)
-	bytePwd := []byte(plainPwd)
-	err := bcrypt.CompareHashAndPassword(byteHash, bytePwd)
+// unlock opens the sealed text of a note with password unless previous failures require waiting.
+// No other verifier of the password exists, so a text that does not open means a wrong password.
+// The guess is counted as failed before the text is opened and forgotten once it opens, so
+// concurrent guesses cannot outrun the limit. A note is destroyed once it reaches its limit.
+func (s *Service) unlock(ctx context.Context, secureNote SecureNote, password string) (string, error) {
+	now := s.now()
+	limit := maxFailedAttempts(secureNote)
+
+	if secureNote.FailedAttempts >= limit {
+		if err := s.repo.DeleteNote(ctx, secureNote.ID); err != nil {
+			return "", fmt.Errorf("delete note after failed attempts: %w", err)
+		}
+		logging.FromContext(ctx).Infow("note destroyed after failed attempts", "noteId", secureNote.ID)
+		return "", ErrNotFound
+	}
+
+	wait := backoff(secureNote.FailedAttempts)
+	if secureNote.FailedAttempts > 0 {
+		retryAt := time.Unix(secureNote.LastFailedAt, 0).Add(wait)
+		if now.Before(retryAt) {
+			return "", &RetryAfterError{RetryAfter: retryAt.Sub(now)}
+		}
+	}
+
+	attempts, err := s.repo.ReserveAttempt(ctx, secureNote.ID, Attempt{
+		Limit:        limit,
+		LastFailedBy: now.Add(-wait).Unix(),
+		At:           now,
+	})
+	if errors.Is(err, ErrAttemptRejected) {
+		// guesses reserved since the note was read took what the limit or backoff allowed
+		return "", &RetryAfterError{RetryAfter: backoff(secureNote.FailedAttempts + 1)}
+	}
 	if err != nil {
-		return false
+		return "", fmt.Errorf("repository reserve attempt: %w", err)
 	}
 
-	return true
+	text, err := s.open(secureNote.Sealed, password)
+	if err == nil {
+		if err := s.repo.ClearFailedAttempts(ctx, secureNote.ID); err != nil {
+			logging.FromContext(ctx).Warnw("clear failed attempts", "noteId", secureNote.ID, "error", err)
+		}
+		return text, nil
+	}
+	if !errors.Is(err, security.ErrDecrypt) {
+		return "", fmt.Errorf("open sealed note text: %w", err)
+	}
+
+	logging.FromContext(ctx).Warnw("wrong note password", "noteId", secureNote.ID, "failedAttempts", attempts)
+
+	if attempts >= limit {
+		if err := s.repo.DeleteNote(ctx, secureNote.ID); err != nil {
+			return "", fmt.Errorf("delete note after failed attempts: %w", err)
+		}
+		logging.FromContext(ctx).Infow("note destroyed after failed attempts", "noteId", secureNote.ID)
+	}
+
+	return "", ErrNotAuthorized
+}
+
+// readBudget returns nil for notes that can be read until they expire
+func readBudget(readsLeft int) *int {
+	if readsLeft == 0 {
+		return nil
+	}
+	return &readsLeft
+}
+
+func (s *Service) expired(ttl int64) bool {
+	return !s.now().Before(time.Unix(ttl, 0))
 }
@@ -2,27 +2,46 @@ package getting_test
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/projects/secure-notes/internal/creating"
 	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/platform/logging"
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/projects/secure-notes/internal/platform/webhook"
+	"github.com/projects/secure-notes/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestService_GetNoteOK(t *testing.T) {
 	// given
 	repository := mockRepository{}
 	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
-		ID:          "qx2rx",
-		Text:        "Hello World",
-		Hash:        "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC",
-		TTL:         time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
-		OneTimeRead: true,
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 1,
 	}, nil)
-	repository.On("DeleteNote", "qx2rx").Return(nil)
+	repository.On("ConsumeRead", "qx2rx", timer()).Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
 
-	s := getting.NewService(&repository)
+	s := getting.NewService(&repository, timer, openSealed, nil)
 
 	// when
 	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
@@ -30,24 +49,128 @@ func TestService_GetNoteOK(t *testing.T) {
 	// then
 	assert.NoError(t, gotErr)
 	assert.Equal(t, getting.Note{
-		ID:   "qx2rx",
-		Text: "Hello World",
-		TTL:  time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ID:        "qx2rx",
+		Text:      "Hello World",
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: intPtr(0),
 	}, gotNote)
 }
 
+func TestService_GetNoteWithReadsLeft(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 3,
+	}, nil)
+	repository.On("ConsumeRead", "qx2rx", timer()).Return(getting.SecureNote{
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 2,
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, getting.Note{
+		ID:        "qx2rx",
+		Text:      "Hello World",
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: intPtr(2),
+	}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteWithoutReadBudget(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("RecordRead", "qx2rx", timer()).Return(nil)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Nil(t, gotNote.ReadsLeft)
+	repository.AssertExpectations(t)
+	repository.AssertNotCalled(t, "ConsumeRead", mock.Anything, mock.Anything)
+}
+
+func TestService_GetNoteRecordReadError(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("RecordRead", "qx2rx", timer()).Return(errors.New("some db error"))
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.EqualError(t, gotErr, "repository record read: some db error")
+	assert.Equal(t, getting.Note{}, gotNote)
+}
+
+func TestService_GetNoteLastReadTakenConcurrently(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 1,
+	}, nil)
+	repository.On("ConsumeRead", "qx2rx", timer()).Return(getting.SecureNote{}, getting.ErrNotFound)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.True(t, errors.Is(gotErr, getting.ErrNotFound), "got %v", gotErr)
+	assert.Equal(t, getting.Note{}, gotNote)
+}
+
 func TestService_GetNoteWrongPassword(t *testing.T) {
 	// given
 	repository := mockRepository{}
 	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
-		ID:          "qx2rx",
-		Text:        "Hello World",
-		Hash:        "$2a$04$tD4EmWTb6FficqPruQNzL.t4X79mud7a3ybAp6JYgf7fItsw3pRoC",
-		TTL:         time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
-		OneTimeRead: true,
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 1,
 	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", getting.Attempt{Limit: getting.DefaultMaxFailedAttempts, LastFailedBy: timer().Unix(), At: timer()}).Return(1, nil)
 
-	s := getting.NewService(&repository)
+	s := getting.NewService(&repository, timer, openSealed, nil)
 
 	// when
 	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "wrongpassword")
@@ -55,6 +178,163 @@ func TestService_GetNoteWrongPassword(t *testing.T) {
 	// then
 	assert.EqualError(t, gotErr, "wrong password")
 	assert.Equal(t, getting.Note{}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteWrongPasswordLoggedWithRequestID(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", getting.Attempt{Limit: getting.DefaultMaxFailedAttempts, LastFailedBy: timer().Unix(), At: timer()}).Return(1, nil)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logging.WithRequestID(context.TODO(), zap.New(core).Sugar(), "c6af9ac6-7b61-11e6-9a41-93e8deadbeef")
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	_, gotErr := s.GetNote(ctx, "qx2rx", "guess-1234")
+
+	// then
+	assert.Equal(t, getting.ErrNotAuthorized, gotErr)
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"requestId":      "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+		"noteId":         "qx2rx",
+		"failedAttempts": int64(1),
+	}, logs.All()[0].ContextMap())
+	assert.NotContains(t, logs.All()[0].Message, "guess-1234")
+}
+
+func TestService_GetNoteBackoffAfterFailedAttempts(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:             "qx2rx",
+		Sealed:         sealedHelloWorld,
+		TTL:            time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		FailedAttempts: 3,
+		LastFailedAt:   timer().Add(-time.Second).Unix(),
+	}, nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	var retryErr *getting.RetryAfterError
+	assert.True(t, errors.As(gotErr, &retryErr))
+	assert.Equal(t, 3*time.Second, retryErr.RetryAfter)
+	assert.True(t, errors.Is(gotErr, getting.ErrTooManyAttempts))
+	assert.Equal(t, getting.Note{}, gotNote)
+}
+
+func TestService_GetNoteAfterBackoffElapsed(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:             "qx2rx",
+		Sealed:         sealedHelloWorld,
+		TTL:            time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		FailedAttempts: 3,
+		LastFailedAt:   timer().Add(-4 * time.Second).Unix(),
+	}, nil)
+	repository.On("RecordRead", "qx2rx", timer()).Return(nil)
+	repository.On("ReserveAttempt", "qx2rx", getting.Attempt{Limit: getting.DefaultMaxFailedAttempts, LastFailedBy: timer().Add(-4 * time.Second).Unix(), At: timer()}).Return(4, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "Hello World", gotNote.Text)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteAttemptRejected(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:             "qx2rx",
+		Sealed:         sealedHelloWorld,
+		TTL:            time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		FailedAttempts: 3,
+		LastFailedAt:   timer().Add(-4 * time.Second).Unix(),
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(0, getting.ErrAttemptRejected)
+
+	opened := false
+	open := func(sealed security.SealedText, pwd string) (string, error) {
+		opened = true
+		return openSealed(sealed, pwd)
+	}
+	s := getting.NewService(&repository, timer, open, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	var retryErr *getting.RetryAfterError
+	assert.True(t, errors.As(gotErr, &retryErr), "got %v", gotErr)
+	assert.Equal(t, 8*time.Second, retryErr.RetryAfter)
+	assert.Equal(t, getting.Note{}, gotNote)
+	assert.False(t, opened, "a guess that could not be reserved must not be checked")
+}
+
+func TestService_GetNoteDestroyedOnLastFailedAttempt(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:                "qx2rx",
+		Sealed:            sealedHelloWorld,
+		TTL:               time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxFailedAttempts: 3,
+		FailedAttempts:    2,
+		LastFailedAt:      timer().Add(-time.Hour).Unix(),
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", getting.Attempt{Limit: 3, LastFailedBy: timer().Add(-2 * time.Second).Unix(), At: timer()}).Return(3, nil)
+	repository.On("DeleteNote", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "wrongpassword")
+
+	// then
+	assert.Equal(t, getting.ErrNotAuthorized, gotErr)
+	assert.Equal(t, getting.Note{}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteOverFailedAttemptsLimit(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:             "qx2rx",
+		Sealed:         sealedHelloWorld,
+		TTL:            time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		FailedAttempts: getting.DefaultMaxFailedAttempts,
+		LastFailedAt:   timer().Add(-time.Hour).Unix(),
+	}, nil)
+	repository.On("DeleteNote", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.Equal(t, getting.ErrNotFound, gotErr)
+	assert.Equal(t, getting.Note{}, gotNote)
+	repository.AssertExpectations(t)
 }
 
 func TestService_GetNoteNotExists(t *testing.T) {
@@ -62,7 +342,7 @@ func TestService_GetNoteNotExists(t *testing.T) {
 	repository := mockRepository{}
 	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{}, getting.ErrNotFound)
 
-	s := getting.NewService(&repository)
+	s := getting.NewService(&repository, timer, openSealed, nil)
 
 	// when
 	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
@@ -72,6 +352,459 @@ func TestService_GetNoteNotExists(t *testing.T) {
 	assert.Equal(t, getting.Note{}, gotNote)
 }
 
+func TestService_GetNoteExpired(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("DeleteNote", "qx2rx").Return(nil)
+
+	afterExpiry := func() time.Time {
+		return time.Date(2020, 3, 22, 16, 0, 1, 0, time.UTC)
+	}
+
+	s := getting.NewService(&repository, afterExpiry, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.Equal(t, getting.ErrExpired, gotErr)
+	assert.Equal(t, getting.Note{}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteExpiredDeleteFails(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("DeleteNote", "qx2rx").Return(errors.New("some db error"))
+
+	atExpiry := func() time.Time {
+		return time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC)
+	}
+
+	s := getting.NewService(&repository, atExpiry, openSealed, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.Equal(t, getting.ErrExpired, gotErr)
+	assert.Equal(t, getting.Note{}, gotNote)
+}
+
+func TestService_GetNoteOpenError(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:     "qx2rx",
+		Sealed: sealedHelloWorld,
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("RecordRead", "qx2rx", timer()).Return(nil)
+
+	failingOpen := func(sealed security.SealedText, pwd string) (string, error) {
+		return "", security.ErrUnsupportedAlgorithm
+	}
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+
+	s := getting.NewService(&repository, timer, failingOpen, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.EqualError(t, gotErr, "open sealed note text: unsupported algorithm")
+	assert.Equal(t, getting.Note{}, gotNote)
+}
+
+func TestService_GetNoteOpenErrorKeepsReadBudget(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:        "qx2rx",
+		Sealed:    sealedHelloWorld,
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 1,
+	}, nil)
+
+	failingOpen := func(sealed security.SealedText, pwd string) (string, error) {
+		return "", security.ErrUnknownPepper
+	}
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+
+	s := getting.NewService(&repository, timer, failingOpen, nil)
+
+	// when
+	_, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.True(t, errors.Is(gotErr, security.ErrUnknownPepper), "got %v", gotErr)
+	repository.AssertNotCalled(t, "ConsumeRead", mock.Anything, mock.Anything)
+	repository.AssertNotCalled(t, "DeleteNote", mock.Anything)
+}
+
+func TestService_GetOpaqueNoteOK(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:         "qx2rx",
+		TTL:        time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:  1,
+		Opaque:     true,
+		Ciphertext: "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:   map[string]string{"alg": "AES-GCM"},
+	}, nil)
+	repository.On("ConsumeRead", "qx2rx", timer()).Return(getting.SecureNote{
+		ID:         "qx2rx",
+		TTL:        time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		Opaque:     true,
+		Ciphertext: "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:   map[string]string{"alg": "AES-GCM"},
+	}, nil)
+
+	failingOpen := func(sealed security.SealedText, pwd string) (string, error) {
+		return "", errors.New("must not open opaque note")
+	}
+
+	s := getting.NewService(&repository, timer, failingOpen, nil)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, getting.Note{
+		ID:         "qx2rx",
+		TTL:        time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		Ciphertext: "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:   map[string]string{"alg": "AES-GCM"},
+		ReadsLeft:  intPtr(0),
+	}, gotNote)
+	repository.AssertExpectations(t)
+}
+
+func TestService_GetNoteWithReadBudgetConcurrently(t *testing.T) {
+	// given
+	const readers = 50
+	repository := memory.NewStorage(timer)
+	_ = repository.CreateNote(context.TODO(), creating.SecureNote{
+		ID:       "qx2rx",
+		Sealed:   sealedHelloWorld,
+		TTL:      time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxReads: 3,
+		// every reader holds a reserved attempt while its password is checked
+		MaxFailedAttempts: readers,
+	})
+
+	s := getting.NewService(repository, timer, openSealed, nil)
+
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		successes int32
+		notFounds int32
+	)
+
+	// when
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := s.GetNote(context.TODO(), "qx2rx", "abc")
+			switch {
+			case err == nil:
+				atomic.AddInt32(&successes, 1)
+			case errors.Is(err, getting.ErrNotFound):
+				atomic.AddInt32(&notFounds, 1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// then
+	assert.Equal(t, int32(3), successes)
+	assert.Equal(t, int32(readers-3), notFounds)
+}
+
+func TestService_GetNoteWrongPasswordConcurrently(t *testing.T) {
+	// given
+	repository := memory.NewStorage(timer)
+	_ = repository.CreateNote(context.TODO(), creating.SecureNote{
+		ID:                "qx2rx",
+		Sealed:            sealedHelloWorld,
+		TTL:               time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxFailedAttempts: 3,
+	})
+
+	var verified int32
+	open := func(sealed security.SealedText, pwd string) (string, error) {
+		atomic.AddInt32(&verified, 1)
+		return openSealed(sealed, pwd)
+	}
+	s := getting.NewService(repository, timer, open, nil)
+
+	const guesses = 50
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+
+	// when
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := s.GetNote(context.TODO(), "qx2rx", "wrongpassword")
+			switch {
+			case errors.Is(err, getting.ErrNotAuthorized),
+				errors.Is(err, getting.ErrTooManyAttempts),
+				errors.Is(err, getting.ErrNotFound):
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// then
+	assert.LessOrEqual(t, atomic.LoadInt32(&verified), int32(3))
+}
+
+func TestService_GetNoteSendsReadReceipt(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:            "qx2rx",
+		Sealed:        sealedHelloWorld,
+		TTL:           time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:     1,
+		WebhookURL:    "https://hooks.example.com/read",
+		WebhookSecret: "whsec",
+	}, nil)
+	repository.On("ConsumeRead", "qx2rx", timer()).Return(getting.SecureNote{
+		ID:            "qx2rx",
+		Sealed:        sealedHelloWorld,
+		TTL:           time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		WebhookURL:    "https://hooks.example.com/read",
+		WebhookSecret: "whsec",
+	}, nil)
+
+	outbox := mockOutbox{}
+	outbox.On("Enqueue", "https://hooks.example.com/read", "whsec", getting.ReadReceipt{
+		Event:     getting.ReadEvent,
+		NoteID:    "qx2rx",
+		ReadAt:    timer().Unix(),
+		ReadsLeft: intPtr(0),
+		Destroyed: true,
+	}).Return(nil)
+	repository.On("ReserveAttempt", "qx2rx", mock.Anything).Return(1, nil)
+	repository.On("ClearFailedAttempts", "qx2rx").Return(nil)
+
+	s := getting.NewService(&repository, timer, openSealed, &outbox)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "Hello World", gotNote.Text)
+	outbox.AssertExpectations(t)
+}
+
+func TestService_GetNoteWithoutReadBudgetSendsReadReceipt(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:            "qx2rx",
+		TTL:           time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		Opaque:        true,
+		Ciphertext:    "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		WebhookURL:    "https://hooks.example.com/read",
+		WebhookSecret: "whsec",
+	}, nil)
+	repository.On("RecordRead", "qx2rx", timer()).Return(nil)
+
+	outbox := mockOutbox{}
+	outbox.On("Enqueue", "https://hooks.example.com/read", "whsec", getting.ReadReceipt{
+		Event:  getting.ReadEvent,
+		NoteID: "qx2rx",
+		ReadAt: timer().Unix(),
+	}).Return(webhook.ErrQueueFull)
+
+	s := getting.NewService(&repository, timer, openSealed, &outbox)
+
+	// when
+	gotNote, gotErr := s.GetNote(context.TODO(), "qx2rx", "")
+
+	// then
+	assert.NoError(t, gotErr, "failed delivery must not fail the read")
+	assert.Equal(t, "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=", gotNote.Ciphertext)
+	outbox.AssertExpectations(t)
+}
+
+func TestService_GetNoteWrongPasswordSendsNoReadReceipt(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNote", "qx2rx").Return(getting.SecureNote{
+		ID:            "qx2rx",
+		Sealed:        sealedHelloWorld,
+		TTL:           time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:     1,
+		WebhookURL:    "https://hooks.example.com/read",
+		WebhookSecret: "whsec",
+	}, nil)
+	repository.On("ReserveAttempt", "qx2rx", getting.Attempt{Limit: getting.DefaultMaxFailedAttempts, LastFailedBy: timer().Unix(), At: timer()}).Return(1, nil)
+
+	outbox := mockOutbox{}
+
+	s := getting.NewService(&repository, timer, openSealed, &outbox)
+
+	// when
+	_, gotErr := s.GetNote(context.TODO(), "qx2rx", "wrong")
+
+	// then
+	assert.Equal(t, getting.ErrNotAuthorized, gotErr)
+	outbox.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_GetNoteReadReceiptDelivered(t *testing.T) {
+	// given
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, webhook.Sign("whsec", body), r.Header.Get(webhook.SignatureHeader))
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	repository := memory.NewStorage(timer)
+	err := repository.CreateNote(context.TODO(), creating.SecureNote{
+		ID:            "qx2rx",
+		Sealed:        sealedHelloWorld,
+		TTL:           time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		MaxReads:      2,
+		WebhookURL:    srv.URL,
+		WebhookSecret: "whsec",
+	})
+	assert.NoError(t, err)
+
+	outbox := webhook.NewOutbox(srv.Client(), webhook.Config{})
+	defer outbox.Close(context.TODO())
+
+	s := getting.NewService(repository, timer, openSealed, outbox)
+
+	// when
+	_, gotErr := s.GetNote(context.TODO(), "qx2rx", "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	select {
+	case body := <-bodies:
+		assert.JSONEq(t, `{"event":"note.read","noteId":"qx2rx","readAt":1584889200,"readsLeft":1,"destroyed":false}`, string(body))
+		assert.NotContains(t, string(body), "Hello World")
+	case <-time.After(5 * time.Second):
+		t.Fatal("read receipt not delivered")
+	}
+}
+
+func TestService_GetNoteMetaOK(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteMeta", "qx2rx").Return(getting.SecureNoteMeta{
+		ID:        "qx2rx",
+		TTL:       time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft: 1,
+	}, nil)
+
+	s := getting.NewService(&repository, timer, openSealed, nil)
+
+	// when
+	gotMeta, gotErr := s.GetNoteMeta(context.TODO(), "qx2rx")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, getting.NoteMeta{
+		ID:               "qx2rx",
+		ExpiresAt:        time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:        intPtr(1),
+		PasswordRequired: true,
+	}, gotMeta)
+	repository.AssertNotCalled(t, "GetNote", "qx2rx")
+	repository.AssertNotCalled(t, "ConsumeRead", mock.Anything, mock.Anything)
+}
+
+func TestService_GetNoteMetaExpired(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteMeta", "qx2rx").Return(getting.SecureNoteMeta{
+		ID:     "qx2rx",
+		TTL:    time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		Opaque: true,
+	}, nil)
+
+	afterExpiry := func() time.Time {
+		return time.Date(2020, 3, 22, 16, 0, 1, 0, time.UTC)
+	}
+
+	s := getting.NewService(&repository, afterExpiry, openSealed, nil)
+
+	// when
+	gotMeta, gotErr := s.GetNoteMeta(context.TODO(), "qx2rx")
+
+	// then
+	assert.Equal(t, getting.ErrExpired, gotErr)
+	assert.Equal(t, getting.NoteMeta{}, gotMeta)
+}
+
+var sealedHelloWorld = security.SealedText{
+	Cipher:     security.CipherAES256GCM,
+	KDF:        security.KDFArgon2id,
+	KDFParams:  security.DefaultKDFParams,
+	Salt:       []byte("0123456789abcdef"),
+	Nonce:      []byte("0123456789ab"),
+	Ciphertext: []byte("sealed Hello World"),
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func timer() time.Time {
+	return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+}
+
+// openSealed opens test notes with the password "abc" only
+func openSealed(sealed security.SealedText, pwd string) (string, error) {
+	if pwd != "abc" {
+		return "", security.ErrDecrypt
+	}
+	return "Hello World", nil
+}
+
+type mockOutbox struct {
+	mock.Mock
+}
+
+func (m *mockOutbox) Enqueue(url, secret string, event interface{}) error {
+	args := m.Called(url, secret, event)
+	return args.Error(0)
+}
+
 type mockRepository struct {
 	mock.Mock
 }
@@ -81,7 +814,32 @@ func (m *mockRepository) GetNote(ctx context.Context, noteID string) (getting.Se
 	return args.Get(0).(getting.SecureNote), args.Error(1)
 }
 
+func (m *mockRepository) GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error) {
+	args := m.Called(noteID)
+	return args.Get(0).(getting.SecureNoteMeta), args.Error(1)
+}
+
 func (m *mockRepository) DeleteNote(ctx context.Context, noteID string) error {
 	args := m.Called(noteID)
 	return args.Error(0)
 }
+
+func (m *mockRepository) ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error) {
+	args := m.Called(noteID, a)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockRepository) ClearFailedAttempts(ctx context.Context, noteID string) error {
+	args := m.Called(noteID)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error) {
+	args := m.Called(noteID, at)
+	return args.Get(0).(getting.SecureNote), args.Error(1)
+}
+
+func (m *mockRepository) RecordRead(ctx context.Context, noteID string, at time.Time) error {
+	args := m.Called(noteID, at)
+	return args.Error(0)
+}
@@ -0,0 +1,27 @@
+package rest
+
+import "net/http"
+
+type noteReader interface {
+	noteGetter
+	noteMetaGetter
+}
+
+type noteManager interface {
+	noteStatusGetter
+	noteUpdater
+	noteDeleter
+}
+
+// NewAPI routes every endpoint of the notes API to its handler
+func NewAPI(nc noteCreator, nr noteReader, nm noteManager) *Router {
+	rt := NewRouter()
+	rt.Handle(http.MethodPost, "/notes", CreateNote(nc))
+	rt.Handle(http.MethodGet, "/notes/{id}", GetNote(nr))
+	rt.Handle(http.MethodPatch, "/notes/{id}", UpdateNote(nm))
+	rt.Handle(http.MethodDelete, "/notes/{id}", DeleteNote(nm))
+	rt.Handle(http.MethodGet, "/notes/{id}/meta", GetNoteMeta(nr))
+	rt.Handle(http.MethodHead, "/notes/{id}/meta", GetNoteMeta(nr))
+	rt.Handle(http.MethodGet, "/notes/{id}/status", GetNoteStatus(nm))
+	return rt
+}
@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+// errorMappings is the single place deciding how domain errors are answered.
+// Expired notes look the same as missing ones, so their existence is not revealed.
+var errorMappings = []web.ErrorMapping{
+	{Err: getting.ErrNotFound, Status: http.StatusNotFound, Code: web.CodeNoteNotFound},
+	{Err: getting.ErrExpired, Status: http.StatusNotFound, Code: web.CodeNoteNotFound},
+	{Err: getting.ErrNotAuthorized, Status: http.StatusUnauthorized, Code: web.CodeInvalidPassword},
+	{Err: managing.ErrNotFound, Status: http.StatusNotFound, Code: web.CodeNoteNotFound},
+	{Err: managing.ErrNotAuthorized, Status: http.StatusUnauthorized, Code: web.CodeNotAuthorized},
+	{
+		Err:    managing.ErrInvalidChange,
+		Status: http.StatusBadRequest,
+		Code:   web.CodeValidationFailed,
+		Detail: managing.ErrInvalidChange.Error(),
+	},
+}
+
+func mapErrors(h web.Handler) web.Handler {
+	return web.MapErrors(h, errorMappings...)
+}
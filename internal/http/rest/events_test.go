@@ -0,0 +1,94 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HandlersServeEveryEventFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		getEvent   string
+		createBody string
+	}{
+		{
+			name: "rest api",
+			getEvent: `{
+				"resource": "/notes/{id}", "path": "/notes/qx2rx", "httpMethod": "GET",
+				"headers": {"PASSWORD": "mySecretPassword"}, "pathParameters": {"id": "qx2rx"}
+			}`,
+			createBody: `{"resource": "/notes", "path": "/notes", "httpMethod": "POST", "body": %q}`,
+		},
+		{
+			name: "http api",
+			getEvent: `{
+				"version": "2.0", "routeKey": "GET /notes/{id}", "rawPath": "/notes/qx2rx",
+				"headers": {"password": "mySecretPassword"}, "pathParameters": {"id": "qx2rx"},
+				"requestContext": {"http": {"method": "GET"}}
+			}`,
+			createBody: `{"version": "2.0", "routeKey": "POST /notes", "rawPath": "/notes", "requestContext": {"http": {"method": "POST"}}, "body": %q}`,
+		},
+		{
+			name: "alb",
+			getEvent: `{
+				"httpMethod": "GET", "path": "/notes/qx2rx",
+				"multiValueHeaders": {"Password": ["mySecretPassword"]},
+				"requestContext": {"elb": {"targetGroupArn": "arn"}}
+			}`,
+			createBody: `{"httpMethod": "POST", "path": "/notes", "requestContext": {"elb": {"targetGroupArn": "arn"}}, "body": %q}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			getter := mockGetService{}
+			getter.On("GetNote", "qx2rx", "mySecretPassword").Return(getting.Note{ID: "qx2rx", Text: "Hello World"}, nil)
+			creator := mockCreateService{}
+			creator.On("CreateNote", creating.Note{Text: "Hello World", Password: "mySecretPassword"}).
+				Return(creating.CreatedNote{ID: "qx2rx"}, nil)
+
+			router := rest.NewRouter()
+			router.Handle(http.MethodGet, "/notes/{id}", rest.GetNote(&getter))
+			router.Handle(http.MethodPost, "/notes", rest.CreateNote(&creator))
+			handler := web.LambdaHandler(router.Serve)
+
+			createEvent := fmt.Sprintf(tt.createBody, `{"text":"Hello World","password":"mySecretPassword"}`)
+
+			// when
+			gotGet, getErr := handler(context.TODO(), json.RawMessage(tt.getEvent))
+			gotCreate, createErr := handler(context.TODO(), json.RawMessage(createEvent))
+
+			// then
+			assert.NoError(t, getErr)
+			assert.NoError(t, createErr)
+			assert.Equal(t, response{StatusCode: http.StatusOK, Body: `{"id":"qx2rx","text":"Hello World","ttl":0}`}, decode(t, gotGet))
+			assert.Equal(t, response{StatusCode: http.StatusCreated, Body: `{"id":"qx2rx","managementToken":""}`}, decode(t, gotCreate))
+		})
+	}
+}
+
+// response holds what every event format answers alike
+type response struct {
+	StatusCode int    `json:"statusCode"`
+	Body       string `json:"body"`
+}
+
+func decode(t *testing.T, event interface{}) response {
+	raw, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var resp response
+	require.NoError(t, json.Unmarshal(raw, &resp))
+	return resp
+}
@@ -3,8 +3,12 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/projects/secure-notes/internal/creating"
 	"github.com/projects/secure-notes/internal/getting"
@@ -12,48 +16,33 @@ import (
 )
 
 type noteCreator interface {
-	CreateNote(ctx context.Context, plain creating.Note) (noteID string, err error)
+	CreateNote(ctx context.Context, plain creating.Note) (creating.CreatedNote, error)
 }
 
 // CreateNote returns a handler for /POST note request
 func CreateNote(nc noteCreator) web.Handler {
-	return func(ctx context.Context, req web.Request) (web.Response, error) {
+	return mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
 		var newNote creating.Note
 		if err := json.Unmarshal([]byte(req.Body), &newNote); err != nil {
-			return web.Response{
-				StatusCode: http.StatusBadRequest,
-			}, err
+			return web.Error(req, http.StatusBadRequest, web.CodeMalformedRequest), err
 		}
 
-		noteID, err := nc.CreateNote(ctx, newNote)
+		created, err := nc.CreateNote(ctx, newNote)
+		var validationErr *creating.ValidationError
+		if errors.As(err, &validationErr) {
+			return validationResponse(req, validationErr), fmt.Errorf("create note: %w", err)
+		}
 		if err != nil {
-			return web.InternalServerError(), fmt.Errorf("create note: %w", err)
+			return web.Response{}, fmt.Errorf("create note: %w", err)
 		}
 
-		resp, err := createNoteResponse(noteID)
+		resp, err := web.JSON(http.StatusCreated, created)
 		if err != nil {
-			return web.InternalServerError(), fmt.Errorf("create response: %w", err)
+			return web.InternalServerError(req), fmt.Errorf("json marshal response: %w", err)
 		}
 
 		return resp, nil
-	}
-}
-
-func createNoteResponse(noteID string) (web.Response, error) {
-	type Response struct {
-		ID string `json:"id"`
-	}
-
-	responseBytes, err := json.Marshal(&Response{ID: noteID})
-	if err != nil {
-		return web.Response{}, fmt.Errorf("json marshal response: %w", err)
-	}
-
-	resp := web.Response{
-		StatusCode: http.StatusCreated,
-		Body:       string(responseBytes),
-	}
-	return resp, nil
+	})
 }
 
 type noteGetter interface {
@@ -62,47 +51,66 @@ type noteGetter interface {
 
 // GetNote returns a handler for /GET note request
 func GetNote(ng noteGetter) web.Handler {
-	return func(ctx context.Context, req web.Request) (web.Response, error) {
+	return mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
 		noteID := req.PathParameters["id"]
-		plainPwd := req.Headers["password"]
+		plainPwd := req.Headers.Get("Password")
 
 		note, err := ng.GetNote(ctx, noteID, plainPwd)
 		if err != nil {
-			switch err {
+			var retryErr *getting.RetryAfterError
+			if errors.As(err, &retryErr) {
+				resp := web.Error(req, http.StatusTooManyRequests, web.CodeRateLimited)
+				resp.Headers.Set("Retry-After", retryAfterSeconds(retryErr.RetryAfter))
+				return resp, fmt.Errorf("get note: %w", err)
+			}
+			return web.Response{}, fmt.Errorf("get note from db: %w", err)
+		}
+
+		resp, err := web.JSON(http.StatusOK, note)
+		if err != nil {
+			return web.InternalServerError(req), fmt.Errorf("json marshal response: %w", err)
+		}
+
+		return resp, nil
+	})
+}
 
-			case getting.ErrNotFound:
-				return web.Response{
-					StatusCode: http.StatusNotFound,
-				}, fmt.Errorf("get note from db: %w", err)
+// retryAfterSeconds rounds up, so clients never retry before the backoff elapses
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
 
-			case getting.ErrNotAuthorized:
-				return web.Response{
-					StatusCode: http.StatusUnauthorized,
-				}, fmt.Errorf("wrong password")
+type noteMetaGetter interface {
+	GetNoteMeta(ctx context.Context, noteID string) (getting.NoteMeta, error)
+}
 
-			default:
-				return web.InternalServerError(), fmt.Errorf("get note from db: %w", err)
-			}
+// GetNoteMeta returns a handler for /GET and /HEAD note meta request.
+// It neither needs the password nor consumes one-time notes.
+func GetNoteMeta(nm noteMetaGetter) web.Handler {
+	return withoutBodyForHead(mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
+		noteID := req.PathParameters["id"]
+
+		meta, err := nm.GetNoteMeta(ctx, noteID)
+		if err != nil {
+			return web.Response{}, fmt.Errorf("get note meta from db: %w", err)
 		}
 
-		resp, err := getNoteResponse(note, err)
+		resp, err := web.JSON(http.StatusOK, meta)
 		if err != nil {
-			return web.InternalServerError(), fmt.Errorf("create response: %w", err)
+			return web.InternalServerError(req), fmt.Errorf("json marshal response: %w", err)
 		}
 
 		return resp, nil
-	}
+	}))
 }
 
-func getNoteResponse(n getting.Note, err error) (web.Response, error) {
-	noteBytes, err := json.Marshal(n)
-	if err != nil {
-		return web.Response{}, fmt.Errorf("json marshal response: %w", err)
-	}
-
-	resp := web.Response{
-		StatusCode: http.StatusOK,
-		Body:       string(noteBytes),
+// withoutBodyForHead drops the body of responses to HEAD requests, keeping their status and headers
+func withoutBodyForHead(h web.Handler) web.Handler {
+	return func(ctx context.Context, req web.Request) (web.Response, error) {
+		resp, err := h(ctx, req)
+		if req.Method == http.MethodHead {
+			resp.Body = ""
+		}
+		return resp, err
 	}
-	return resp, nil
 }
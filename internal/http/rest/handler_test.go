@@ -3,10 +3,13 @@ package rest_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
 	"github.com/projects/secure-notes/internal/http/rest"
 	"github.com/projects/secure-notes/internal/platform/web"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +24,7 @@ func Test_CreateNoteOK(t *testing.T) {
 		Password:        "mySecretPassword",
 		LifeTimeSeconds: 360000,
 		OneTimeRead:     true,
-	}).Return("qx2rx", nil)
+	}).Return(creating.CreatedNote{ID: "qx2rx", ManagementToken: "mgmt-token"}, nil)
 
 	handler := rest.CreateNote(&service)
 
@@ -40,7 +43,8 @@ func Test_CreateNoteOK(t *testing.T) {
 	// then
 	assert.Equal(t, web.Response{
 		StatusCode: http.StatusCreated,
-		Body:       `{"id":"qx2rx"}`,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"id":"qx2rx","managementToken":"mgmt-token"}`,
 	}, gotResp)
 	assert.NoError(t, gotErr)
 }
@@ -53,7 +57,7 @@ func Test_CreateNoteInternalError(t *testing.T) {
 		Password:        "mySecretPassword",
 		LifeTimeSeconds: 360000,
 		OneTimeRead:     true,
-	}).Return("", errors.New("some db error details"))
+	}).Return(creating.CreatedNote{}, errors.New("some db error details"))
 
 	handler := rest.CreateNote(&service)
 
@@ -72,16 +76,248 @@ func Test_CreateNoteInternalError(t *testing.T) {
 	// then
 	assert.Equal(t, web.Response{
 		StatusCode: http.StatusInternalServerError,
-		Body:       "Internal server error",
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 	}, gotResp)
 	assert.EqualError(t, gotErr, "create note: some db error details")
 }
 
+func Test_CreateNoteMixedModes(t *testing.T) {
+	// given
+	service := mockCreateService{}
+	service.On("CreateNote", creating.Note{
+		Text:            "Hello World",
+		Password:        "mySecretPassword",
+		LifeTimeSeconds: 360000,
+		Ciphertext:      "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+	}).Return(creating.CreatedNote{}, &creating.ValidationError{Fields: []creating.FieldError{
+		{Field: "ciphertext", Err: creating.ErrMixedModes},
+	}})
+
+	handler := rest.CreateNote(&service)
+
+	request := web.Request{
+		Body: `{
+				"text": "Hello World",
+				"lifeTimeSeconds": 360000,
+				"password": "mySecretPassword",
+				"ciphertext": "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y="
+			}`,
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Note is invalid","status":422,"code":"validation_failed","invalidParams":[{"name":"ciphertext","reason":"note cannot have both ciphertext and text or password"}]}`,
+	}, gotResp)
+	assert.EqualError(t, gotErr, "create note: invalid note: ciphertext: note cannot have both ciphertext and text or password")
+}
+
+func Test_CreateNoteTooLarge(t *testing.T) {
+	// given
+	service := mockCreateService{}
+	service.On("CreateNote", mock.Anything).Return(creating.CreatedNote{}, &creating.ValidationError{Fields: []creating.FieldError{
+		{Field: "text", Err: creating.ErrTextTooLong},
+		{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+	}})
+
+	handler := rest.CreateNote(&service)
+
+	request := web.Request{
+		Body: `{"text": "Hello World", "password": "mySecretPassword", "lifeTimeSeconds": 0}`,
+	}
+
+	// when
+	gotResp, _ := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Note is invalid","status":413,"code":"validation_failed","invalidParams":[{"name":"text","reason":"text is too long"},{"name":"lifeTimeSeconds","reason":"lifeTimeSeconds is out of range"}]}`,
+	}, gotResp)
+}
+
+func Test_CreateNoteMalformedBody(t *testing.T) {
+	// given
+	service := mockCreateService{}
+	handler := rest.CreateNote(&service)
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), web.Request{Body: `{"text": `})
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, gotResp.StatusCode)
+	assert.Error(t, gotErr)
+	service.AssertNotCalled(t, "CreateNote", mock.Anything)
+}
+
+func Test_GetNoteAlreadyConsumed(t *testing.T) {
+	// given
+	service := mockGetService{}
+	service.On("GetNote", "qx2rx", "mySecretPassword").
+		Return(getting.Note{}, fmt.Errorf("repository consume note: %w", getting.ErrNotFound))
+
+	handler := rest.GetNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Password": {"mySecretPassword"}},
+	}
+	request.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusNotFound,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Not Found","status":404,"code":"note_not_found","requestId":"c6af9ac6-7b61-11e6-9a41-93e8deadbeef"}`,
+	}, gotResp)
+	assert.EqualError(t, gotErr, "get note from db: repository consume note: note not found")
+}
+
+func Test_GetNoteExpiredLooksLikeNotFound(t *testing.T) {
+	// given
+	service := mockGetService{}
+	service.On("GetNote", "expired", "mySecretPassword").Return(getting.Note{}, getting.ErrExpired)
+	service.On("GetNote", "missing", "mySecretPassword").Return(getting.Note{}, getting.ErrNotFound)
+
+	handler := rest.GetNote(&service)
+
+	request := func(noteID string) web.Request {
+		return web.Request{
+			PathParameters: map[string]string{"id": noteID},
+			Headers:        http.Header{"Password": {"mySecretPassword"}},
+		}
+	}
+
+	// when
+	gotExpiredResp, gotExpiredErr := handler(context.TODO(), request("expired"))
+	gotMissingResp, _ := handler(context.TODO(), request("missing"))
+
+	// then
+	assert.Equal(t, gotMissingResp, gotExpiredResp)
+	assert.Equal(t, http.StatusNotFound, gotExpiredResp.StatusCode)
+	assert.True(t, errors.Is(gotExpiredErr, getting.ErrExpired))
+}
+
+func Test_GetNoteTooManyAttempts(t *testing.T) {
+	// given
+	service := mockGetService{}
+	service.On("GetNote", "qx2rx", "guess").
+		Return(getting.Note{}, &getting.RetryAfterError{RetryAfter: 1500 * time.Millisecond})
+
+	handler := rest.GetNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Password": {"guess"}},
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}, "Retry-After": {"2"}},
+		Body:       `{"type":"about:blank","title":"Too Many Requests","status":429,"code":"rate_limited"}`,
+	}, gotResp)
+	assert.True(t, errors.Is(gotErr, getting.ErrTooManyAttempts))
+}
+
+func Test_GetNoteMetaOK(t *testing.T) {
+	// given
+	service := mockGetMetaService{}
+	service.On("GetNoteMeta", "qx2rx").Return(getting.NoteMeta{
+		ID:               "qx2rx",
+		ExpiresAt:        1584892800,
+		ReadsLeft:        intPtr(1),
+		PasswordRequired: true,
+	}, nil)
+
+	handler := rest.GetNoteMeta(&service)
+
+	request := web.Request{
+		Method:         http.MethodGet,
+		PathParameters: map[string]string{"id": "qx2rx"},
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"id":"qx2rx","expiresAt":1584892800,"readsLeft":1,"passwordRequired":true}`,
+	}, gotResp)
+}
+
+func Test_HeadNoteMeta(t *testing.T) {
+	// given
+	service := mockGetMetaService{}
+	service.On("GetNoteMeta", "qx2rx").Return(getting.NoteMeta{ID: "qx2rx"}, nil)
+	service.On("GetNoteMeta", "missing").Return(getting.NoteMeta{}, getting.ErrNotFound)
+
+	handler := rest.GetNoteMeta(&service)
+
+	request := func(noteID string) web.Request {
+		return web.Request{
+			Method:         http.MethodHead,
+			PathParameters: map[string]string{"id": noteID},
+		}
+	}
+
+	// when
+	gotExistingResp, _ := handler(context.TODO(), request("qx2rx"))
+	gotMissingResp, _ := handler(context.TODO(), request("missing"))
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+	}, gotExistingResp)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusNotFound,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+	}, gotMissingResp)
+}
+
 type mockCreateService struct {
 	mock.Mock
 }
 
-func (m *mockCreateService) CreateNote(ctx context.Context, plain creating.Note) (noteID string, err error) {
+func (m *mockCreateService) CreateNote(ctx context.Context, plain creating.Note) (creating.CreatedNote, error) {
 	args := m.Called(plain)
-	return args.String(0), args.Error(1)
+	return args.Get(0).(creating.CreatedNote), args.Error(1)
+}
+
+type mockGetService struct {
+	mock.Mock
+}
+
+func (m *mockGetService) GetNote(ctx context.Context, noteID, password string) (getting.Note, error) {
+	args := m.Called(noteID, password)
+	return args.Get(0).(getting.Note), args.Error(1)
+}
+
+type mockGetMetaService struct {
+	mock.Mock
+}
+
+func (m *mockGetMetaService) GetNoteMeta(ctx context.Context, noteID string) (getting.NoteMeta, error) {
+	args := m.Called(noteID)
+	return args.Get(0).(getting.NoteMeta), args.Error(1)
+}
+
+func intPtr(i int) *int {
+	return &i
 }
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+type noteStatusGetter interface {
+	GetStatus(ctx context.Context, noteID, token string) (managing.Status, error)
+}
+
+// GetNoteStatus returns a handler for /GET note status request authorized by the management token
+func GetNoteStatus(ns noteStatusGetter) web.Handler {
+	return mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
+		status, err := ns.GetStatus(ctx, req.PathParameters["id"], managementToken(req))
+		if err != nil {
+			return web.Response{}, fmt.Errorf("get note status: %w", err)
+		}
+
+		return statusResponse(req, status)
+	})
+}
+
+type noteUpdater interface {
+	UpdateNote(ctx context.Context, noteID, token string, c managing.Change) (managing.Status, error)
+}
+
+// UpdateNote returns a handler for /PATCH note request authorized by the management token
+func UpdateNote(nu noteUpdater) web.Handler {
+	return mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
+		var change managing.Change
+		if err := json.Unmarshal([]byte(req.Body), &change); err != nil {
+			return web.Error(req, http.StatusBadRequest, web.CodeMalformedRequest), err
+		}
+
+		status, err := nu.UpdateNote(ctx, req.PathParameters["id"], managementToken(req), change)
+		var validationErr *creating.ValidationError
+		if errors.As(err, &validationErr) {
+			return validationResponse(req, validationErr), fmt.Errorf("update note: %w", err)
+		}
+		if err != nil {
+			return web.Response{}, fmt.Errorf("update note: %w", err)
+		}
+
+		return statusResponse(req, status)
+	})
+}
+
+type noteDeleter interface {
+	DeleteNote(ctx context.Context, noteID, token string) error
+}
+
+// DeleteNote returns a handler for /DELETE note request authorized by the management token
+func DeleteNote(nd noteDeleter) web.Handler {
+	return mapErrors(func(ctx context.Context, req web.Request) (web.Response, error) {
+		if err := nd.DeleteNote(ctx, req.PathParameters["id"], managementToken(req)); err != nil {
+			return web.Response{}, fmt.Errorf("delete note: %w", err)
+		}
+
+		return web.Response{StatusCode: http.StatusNoContent}, nil
+	})
+}
+
+// managementToken reads the bearer token from the Authorization header
+func managementToken(req web.Request) string {
+	const prefix = "Bearer "
+	value := req.Headers.Get("Authorization")
+	if !strings.HasPrefix(value, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(value, prefix)
+}
+
+func statusResponse(req web.Request, status managing.Status) (web.Response, error) {
+	resp, err := web.JSON(http.StatusOK, status)
+	if err != nil {
+		return web.InternalServerError(req), fmt.Errorf("json marshal response: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,220 @@
+package rest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_GetNoteStatusOK(t *testing.T) {
+	// given
+	service := mockManageService{}
+	service.On("GetStatus", "qx2rx", "mgmt-token").Return(managing.Status{
+		ID:        "qx2rx",
+		ExpiresAt: 1584892800,
+		ReadsLeft: intPtr(1),
+	}, nil)
+
+	handler := rest.GetNoteStatus(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Bearer mgmt-token"}},
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"id":"qx2rx","expiresAt":1584892800,"read":false,"readCount":0,"readsLeft":1}`,
+	}, gotResp)
+}
+
+func Test_GetNoteStatusErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "not found", err: managing.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "not authorized", err: managing.ErrNotAuthorized, wantStatus: http.StatusUnauthorized},
+		{name: "db error", err: errors.New("some db error"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			service := mockManageService{}
+			service.On("GetStatus", "qx2rx", "").Return(managing.Status{}, tt.err)
+
+			handler := rest.GetNoteStatus(&service)
+
+			request := web.Request{
+				PathParameters: map[string]string{"id": "qx2rx"},
+			}
+
+			// when
+			gotResp, gotErr := handler(context.TODO(), request)
+
+			// then
+			assert.Equal(t, tt.wantStatus, gotResp.StatusCode)
+			assert.True(t, errors.Is(gotErr, tt.err))
+		})
+	}
+}
+
+func Test_UpdateNoteOK(t *testing.T) {
+	// given
+	lifeTime := int64(600)
+	service := mockManageService{}
+	service.On("UpdateNote", "qx2rx", "mgmt-token", managing.Change{LifeTimeSeconds: &lifeTime}).
+		Return(managing.Status{ID: "qx2rx", ExpiresAt: 1584889800}, nil)
+
+	handler := rest.UpdateNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Bearer mgmt-token"}},
+		Body:           `{"lifeTimeSeconds": 600}`,
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"id":"qx2rx","expiresAt":1584889800,"read":false,"readCount":0}`,
+	}, gotResp)
+}
+
+func Test_UpdateNoteInvalidChange(t *testing.T) {
+	// given
+	service := mockManageService{}
+	service.On("UpdateNote", "qx2rx", "mgmt-token", managing.Change{}).
+		Return(managing.Status{}, managing.ErrInvalidChange)
+
+	handler := rest.UpdateNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Bearer mgmt-token"}},
+		Body:           `{}`,
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusBadRequest,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"change must set a positive lifeTimeSeconds or readsLeft","code":"validation_failed"}`,
+	}, gotResp)
+	assert.True(t, errors.Is(gotErr, managing.ErrInvalidChange))
+}
+
+func Test_UpdateNoteLifetimeBeyondLimits(t *testing.T) {
+	// given
+	lifetime := int64(31 * 24 * 3600)
+	service := mockManageService{}
+	service.On("UpdateNote", "qx2rx", "mgmt-token", managing.Change{LifeTimeSeconds: &lifetime}).
+		Return(managing.Status{}, &creating.ValidationError{Fields: []creating.FieldError{
+			{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+		}})
+
+	handler := rest.UpdateNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Bearer mgmt-token"}},
+		Body:           `{"lifeTimeSeconds":2678400}`,
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Note is invalid","status":422,"code":"validation_failed","invalidParams":[{"name":"lifeTimeSeconds","reason":"lifeTimeSeconds is out of range"}]}`,
+	}, gotResp)
+	assert.True(t, errors.Is(gotErr, creating.ErrLifetimeOutOfRange))
+}
+
+func Test_DeleteNoteOK(t *testing.T) {
+	// given
+	service := mockManageService{}
+	service.On("DeleteNote", "qx2rx", "mgmt-token").Return(nil)
+
+	handler := rest.DeleteNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Bearer mgmt-token"}},
+	}
+
+	// when
+	gotResp, gotErr := handler(context.TODO(), request)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{StatusCode: http.StatusNoContent}, gotResp)
+}
+
+func Test_DeleteNoteRequiresBearerToken(t *testing.T) {
+	// given
+	service := mockManageService{}
+	service.On("DeleteNote", "qx2rx", "").Return(managing.ErrNotAuthorized)
+
+	handler := rest.DeleteNote(&service)
+
+	request := web.Request{
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Headers:        http.Header{"Authorization": {"Basic bWdtdC10b2tlbg=="}},
+	}
+
+	// when
+	gotResp, _ := handler(context.TODO(), request)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusUnauthorized,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Unauthorized","status":401,"code":"not_authorized"}`,
+	}, gotResp)
+}
+
+type mockManageService struct {
+	mock.Mock
+}
+
+func (m *mockManageService) GetStatus(ctx context.Context, noteID, token string) (managing.Status, error) {
+	args := m.Called(noteID, token)
+	return args.Get(0).(managing.Status), args.Error(1)
+}
+
+func (m *mockManageService) UpdateNote(ctx context.Context, noteID, token string, c managing.Change) (managing.Status, error) {
+	args := m.Called(noteID, token, c)
+	return args.Get(0).(managing.Status), args.Error(1)
+}
+
+func (m *mockManageService) DeleteNote(ctx context.Context, noteID, token string) error {
+	args := m.Called(noteID, token)
+	return args.Error(0)
+}
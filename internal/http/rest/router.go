@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+// Router dispatches requests to handlers by method and path template, such as /notes/{id}.
+// It lets a single function or server answer every route of the API.
+type Router struct {
+	routes []*route
+}
+
+type route struct {
+	template string
+	segments []string
+	handlers map[string]web.Handler
+}
+
+// NewRouter provides a router without any routes
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers h for requests with method to paths matching template.
+// Segments in braces match any non-empty path segment and are passed in PathParameters.
+func (rt *Router) Handle(method, template string, h web.Handler) {
+	for _, r := range rt.routes {
+		if r.template == template {
+			r.handlers[method] = h
+			return
+		}
+	}
+
+	rt.routes = append(rt.routes, &route{
+		template: template,
+		segments: strings.Split(strings.TrimPrefix(template, "/"), "/"),
+		handlers: map[string]web.Handler{method: h},
+	})
+}
+
+// Serve answers req with the handler of the matching route. Unknown paths get 404,
+// other methods of a known path 405, and OPTIONS requests 204, each listing allowed methods.
+func (rt *Router) Serve(ctx context.Context, req web.Request) (web.Response, error) {
+	r, params := rt.match(req)
+	if r == nil {
+		return web.Error(req, http.StatusNotFound, web.CodeRouteNotFound), nil
+	}
+
+	h, ok := r.handlers[req.Method]
+	if !ok {
+		allow := strings.Join(r.methods(), ", ")
+		if req.Method == http.MethodOptions {
+			return web.Response{StatusCode: http.StatusNoContent, Headers: http.Header{"Allow": {allow}}}, nil
+		}
+		resp := web.Error(req, http.StatusMethodNotAllowed, web.CodeMethodNotAllowed)
+		resp.Headers.Set("Allow", allow)
+		return resp, nil
+	}
+
+	req.PathParameters = params
+	return h(ctx, req)
+}
+
+// match finds the route of req. API Gateway already resolved the template into Route and
+// filled path parameters, otherwise they are taken from the path, as in the standalone server.
+func (rt *Router) match(req web.Request) (*route, map[string]string) {
+	for _, r := range rt.routes {
+		if req.Route == r.template {
+			return r, req.PathParameters
+		}
+	}
+
+	segments := strings.Split(strings.TrimPrefix(req.Path, "/"), "/")
+	for _, r := range rt.routes {
+		if params, ok := r.params(segments); ok {
+			return r, params
+		}
+	}
+	return nil, nil
+}
+
+func (r *route) params(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, s := range r.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[s[1:len(s)-1]] = segments[i]
+			continue
+		}
+		if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (r *route) methods() []string {
+	methods := make([]string, 0, len(r.handlers))
+	for m := range r.handlers {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
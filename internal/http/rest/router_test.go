@@ -0,0 +1,116 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/http/rest"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func echoRoute(name string) web.Handler {
+	return func(ctx context.Context, req web.Request) (web.Response, error) {
+		return web.Response{StatusCode: http.StatusOK, Body: name + " " + req.PathParameters["id"]}, nil
+	}
+}
+
+func newTestRouter() *rest.Router {
+	rt := rest.NewRouter()
+	rt.Handle(http.MethodPost, "/notes", echoRoute("create"))
+	rt.Handle(http.MethodGet, "/notes/{id}", echoRoute("get"))
+	rt.Handle(http.MethodDelete, "/notes/{id}", echoRoute("delete"))
+	rt.Handle(http.MethodGet, "/notes/{id}/meta", echoRoute("meta"))
+	return rt
+}
+
+func Test_RouterDispatchesByMethodAndPath(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{method: http.MethodPost, path: "/notes", want: "create "},
+		{method: http.MethodGet, path: "/notes/qx2rx", want: "get qx2rx"},
+		{method: http.MethodDelete, path: "/notes/qx2rx", want: "delete qx2rx"},
+		{method: http.MethodGet, path: "/notes/qx2rx/meta", want: "meta qx2rx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			// given
+			req := web.Request{Method: tt.method, Path: tt.path}
+
+			// when
+			gotResp, gotErr := newTestRouter().Serve(context.TODO(), req)
+
+			// then
+			assert.NoError(t, gotErr)
+			assert.Equal(t, http.StatusOK, gotResp.StatusCode)
+			assert.Equal(t, tt.want, gotResp.Body)
+		})
+	}
+}
+
+func Test_RouterKeepsAPIGatewayPathParameters(t *testing.T) {
+	// given
+	req := web.Request{
+		Method:         http.MethodGet,
+		Route:          "/notes/{id}",
+		Path:           "/v1/notes/qx2rx",
+		PathParameters: map[string]string{"id": "qx2rx"},
+	}
+
+	// when
+	gotResp, _ := newTestRouter().Serve(context.TODO(), req)
+
+	// then
+	assert.Equal(t, "get qx2rx", gotResp.Body)
+}
+
+func Test_RouterMethodNotAllowed(t *testing.T) {
+	// given
+	req := web.Request{Method: http.MethodPatch, Path: "/notes/qx2rx"}
+	req.RequestID = "req-1"
+
+	// when
+	gotResp, gotErr := newTestRouter().Serve(context.TODO(), req)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusMethodNotAllowed,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}, "Allow": {"DELETE, GET"}},
+		Body:       `{"type":"about:blank","title":"Method Not Allowed","status":405,"code":"method_not_allowed","requestId":"req-1"}`,
+	}, gotResp)
+}
+
+func Test_RouterAnswersOptionsWithAllowedMethods(t *testing.T) {
+	// given
+	req := web.Request{Method: http.MethodOptions, Path: "/notes"}
+
+	// when
+	gotResp, _ := newTestRouter().Serve(context.TODO(), req)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusNoContent,
+		Headers:    http.Header{"Allow": {"POST"}},
+	}, gotResp)
+}
+
+func Test_RouterNotFound(t *testing.T) {
+	for _, path := range []string{"/", "/notes/", "/notes/qx2rx/unknown", "/other"} {
+		t.Run(path, func(t *testing.T) {
+			// given
+			req := web.Request{Method: http.MethodGet, Path: path}
+
+			// when
+			gotResp, _ := newTestRouter().Serve(context.TODO(), req)
+
+			// then
+			assert.Equal(t, http.StatusNotFound, gotResp.StatusCode)
+			assert.Contains(t, gotResp.Body, `"code":"route_not_found"`)
+		})
+	}
+}
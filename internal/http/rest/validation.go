@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+// validationResponse answers with 413 when a note exceeds size limits and with 422 otherwise
+func validationResponse(req web.Request, verr *creating.ValidationError) web.Response {
+	status := http.StatusUnprocessableEntity
+	if tooLarge(verr) {
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	problem := web.Problem{Title: "Note is invalid"}
+	for _, f := range verr.Fields {
+		problem.InvalidParams = append(problem.InvalidParams, web.InvalidParam{Name: f.Field, Reason: f.Err.Error()})
+	}
+
+	return web.ProblemResponse(req, status, web.CodeValidationFailed, problem)
+}
+
+func tooLarge(err error) bool {
+	return errors.Is(err, creating.ErrTextTooLong) ||
+		errors.Is(err, creating.ErrCiphertextTooLong) ||
+		errors.Is(err, creating.ErrMetadataTooLong)
+}
@@ -0,0 +1,39 @@
+package managing
+
+// SecureNoteStatus defines properties of a stored note needed to manage it.
+// Consumed notes had their last read taken and kept no content, only their status.
+type SecureNoteStatus struct {
+	ID                  string
+	ManagementTokenHash string
+	TTL                 int64
+	ReadsLeft           int
+	ReadCount           int
+	LastReadAt          int64
+	Consumed            bool
+}
+
+// Status tells the creator whether and when a note was read.
+// ReadsLeft is nil for notes that can be read until they expire.
+type Status struct {
+	ID         string `json:"id"`
+	ExpiresAt  int64  `json:"expiresAt"`
+	Read       bool   `json:"read"`
+	ReadCount  int    `json:"readCount"`
+	LastReadAt *int64 `json:"lastReadAt,omitempty"`
+	ReadsLeft  *int   `json:"readsLeft,omitempty"`
+}
+
+// Change defines what a creator may change on a note; nil fields are kept as they are.
+// LifeTimeSeconds counts from the time of the change and may extend or shorten the note.
+// ReadsLeft must be positive: a budget is never removed, as a leaked token must not make a
+// note readable until it expires, and revoking a note is done by deleting it.
+type Change struct {
+	LifeTimeSeconds *int64 `json:"lifeTimeSeconds"`
+	ReadsLeft       *int   `json:"readsLeft"`
+}
+
+// NoteUpdate defines new values of a stored note; nil fields are kept as they are
+type NoteUpdate struct {
+	TTL       *int64
+	ReadsLeft *int
+}
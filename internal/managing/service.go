@@ -0,0 +1,150 @@
+package managing
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+)
+
+var (
+	// ErrNotFound is used when a note could not be found or has expired.
+	ErrNotFound = errors.New("note not found")
+
+	// ErrNotAuthorized is used when the management token does not match the note.
+	ErrNotAuthorized = errors.New("wrong management token")
+
+	// ErrInvalidChange is used when a change is empty or its values are out of range.
+	ErrInvalidChange = errors.New("change must set a positive lifeTimeSeconds or readsLeft")
+)
+
+// Service lets creators inspect, change and revoke their notes
+type Service struct {
+	repo      repository
+	now       func() time.Time
+	hashToken func(token string) string
+	limits    creating.Limits
+}
+
+type repository interface {
+	GetNoteStatus(ctx context.Context, noteID string) (SecureNoteStatus, error)
+	// UpdateNote must fail with ErrNotFound for missing or consumed notes
+	UpdateNote(ctx context.Context, noteID string, u NoteUpdate) (SecureNoteStatus, error)
+	DeleteNote(ctx context.Context, noteID string) error
+}
+
+// NewService provides managing note service. Changed lifetimes must stay within the lifetime
+// limits of new notes, so a note cannot outlive what creating it would allow.
+func NewService(r repository, now func() time.Time, hashToken func(token string) string, limits creating.Limits) *Service {
+	return &Service{repo: r, now: now, hashToken: hashToken, limits: limits}
+}
+
+// GetStatus tells whether, when and how many times a note was read
+func (s *Service) GetStatus(ctx context.Context, noteID, token string) (Status, error) {
+	status, err := s.authorize(ctx, noteID, token)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return toStatus(status), nil
+}
+
+// UpdateNote changes lifetime or remaining reads of a note that was not consumed yet.
+// Lifetimes outside of the limits are rejected with *creating.ValidationError.
+func (s *Service) UpdateNote(ctx context.Context, noteID, token string, c Change) (Status, error) {
+	if !valid(c) {
+		return Status{}, ErrInvalidChange
+	}
+	if lt := c.LifeTimeSeconds; lt != nil && (*lt < s.limits.MinLifeTimeSeconds || *lt > s.limits.MaxLifeTimeSeconds) {
+		return Status{}, &creating.ValidationError{Fields: []creating.FieldError{
+			{Field: "lifeTimeSeconds", Err: creating.ErrLifetimeOutOfRange},
+		}}
+	}
+
+	if _, err := s.authorize(ctx, noteID, token); err != nil {
+		return Status{}, err
+	}
+
+	u := NoteUpdate{ReadsLeft: c.ReadsLeft}
+	if c.LifeTimeSeconds != nil {
+		ttl := s.now().Add(time.Duration(*c.LifeTimeSeconds) * time.Second).Unix()
+		u.TTL = &ttl
+	}
+
+	status, err := s.repo.UpdateNote(ctx, noteID, u)
+	if err != nil {
+		return Status{}, fmt.Errorf("repository update note: %w", err)
+	}
+
+	return toStatus(status), nil
+}
+
+// DeleteNote revokes a note before it is read or expires
+func (s *Service) DeleteNote(ctx context.Context, noteID, token string) error {
+	if _, err := s.authorize(ctx, noteID, token); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteNote(ctx, noteID); err != nil {
+		return fmt.Errorf("repository delete note: %w", err)
+	}
+
+	return nil
+}
+
+// authorize loads a note that has not expired and whose management token matches.
+// Notes created before management tokens have no hash and cannot be managed.
+func (s *Service) authorize(ctx context.Context, noteID, token string) (SecureNoteStatus, error) {
+	status, err := s.repo.GetNoteStatus(ctx, noteID)
+	if err != nil {
+		return SecureNoteStatus{}, fmt.Errorf("repository get note status: %w", err)
+	}
+
+	if !s.now().Before(time.Unix(status.TTL, 0)) {
+		return SecureNoteStatus{}, ErrNotFound
+	}
+
+	if status.ManagementTokenHash == "" || token == "" {
+		return SecureNoteStatus{}, ErrNotAuthorized
+	}
+	hash := s.hashToken(token)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(status.ManagementTokenHash)) != 1 {
+		return SecureNoteStatus{}, ErrNotAuthorized
+	}
+
+	return status, nil
+}
+
+func valid(c Change) bool {
+	if c.LifeTimeSeconds == nil && c.ReadsLeft == nil {
+		return false
+	}
+	if c.LifeTimeSeconds != nil && *c.LifeTimeSeconds <= 0 {
+		return false
+	}
+	if c.ReadsLeft != nil && *c.ReadsLeft <= 0 {
+		return false
+	}
+	return true
+}
+
+func toStatus(sn SecureNoteStatus) Status {
+	status := Status{
+		ID:        sn.ID,
+		ExpiresAt: sn.TTL,
+		Read:      sn.ReadCount > 0,
+		ReadCount: sn.ReadCount,
+	}
+	if sn.LastReadAt != 0 {
+		lastReadAt := sn.LastReadAt
+		status.LastReadAt = &lastReadAt
+	}
+	if sn.ReadsLeft > 0 || sn.Consumed {
+		readsLeft := sn.ReadsLeft
+		status.ReadsLeft = &readsLeft
+	}
+	return status
+}
@@ -0,0 +1,306 @@
+package managing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_GetStatusOK(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:           2,
+		ReadCount:           1,
+		LastReadAt:          time.Date(2020, 3, 22, 14, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	gotStatus, gotErr := s.GetStatus(context.TODO(), "qx2rx", "mgmt-token")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, managing.Status{
+		ID:         "qx2rx",
+		ExpiresAt:  time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		Read:       true,
+		ReadCount:  1,
+		LastReadAt: int64Ptr(time.Date(2020, 3, 22, 14, 0, 0, 0, time.UTC).Unix()),
+		ReadsLeft:  intPtr(2),
+	}, gotStatus)
+}
+
+func TestService_GetStatusOfConsumedNote(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadCount:           1,
+		LastReadAt:          time.Date(2020, 3, 22, 14, 0, 0, 0, time.UTC).Unix(),
+		Consumed:            true,
+	}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	gotStatus, gotErr := s.GetStatus(context.TODO(), "qx2rx", "mgmt-token")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.True(t, gotStatus.Read)
+	assert.Equal(t, intPtr(0), gotStatus.ReadsLeft)
+}
+
+func TestService_GetStatusNotAuthorized(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenHash string
+		token     string
+	}{
+		{name: "wrong token", tokenHash: "hashed:mgmt-token", token: "guess"},
+		{name: "missing token", tokenHash: "hashed:mgmt-token", token: ""},
+		{name: "note without management token", tokenHash: "", token: "mgmt-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			repository := mockRepository{}
+			repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+				ID:                  "qx2rx",
+				ManagementTokenHash: tt.tokenHash,
+				TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+			}, nil)
+
+			s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+			// when
+			gotStatus, gotErr := s.GetStatus(context.TODO(), "qx2rx", tt.token)
+
+			// then
+			assert.Equal(t, managing.ErrNotAuthorized, gotErr)
+			assert.Equal(t, managing.Status{}, gotStatus)
+		})
+	}
+}
+
+func TestService_GetStatusExpired(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	_, gotErr := s.GetStatus(context.TODO(), "qx2rx", "mgmt-token")
+
+	// then
+	assert.Equal(t, managing.ErrNotFound, gotErr)
+}
+
+func TestService_GetStatusNotExists(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{}, managing.ErrNotFound)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	_, gotErr := s.GetStatus(context.TODO(), "qx2rx", "mgmt-token")
+
+	// then
+	assert.True(t, errors.Is(gotErr, managing.ErrNotFound))
+}
+
+func TestService_UpdateNoteOK(t *testing.T) {
+	// given
+	newTTL := time.Date(2020, 3, 22, 17, 0, 0, 0, time.UTC).Unix()
+
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+		ReadsLeft:           1,
+	}, nil)
+	repository.On("UpdateNote", "qx2rx", managing.NoteUpdate{TTL: &newTTL, ReadsLeft: intPtr(3)}).
+		Return(managing.SecureNoteStatus{
+			ID:                  "qx2rx",
+			ManagementTokenHash: "hashed:mgmt-token",
+			TTL:                 newTTL,
+			ReadsLeft:           3,
+		}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	gotStatus, gotErr := s.UpdateNote(context.TODO(), "qx2rx", "mgmt-token", managing.Change{
+		LifeTimeSeconds: int64Ptr(2 * 3600),
+		ReadsLeft:       intPtr(3),
+	})
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, managing.Status{
+		ID:        "qx2rx",
+		ExpiresAt: newTTL,
+		ReadsLeft: intPtr(3),
+	}, gotStatus)
+	repository.AssertExpectations(t)
+}
+
+func TestService_UpdateNoteInvalidChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change managing.Change
+	}{
+		{name: "empty change", change: managing.Change{}},
+		{name: "zero lifetime", change: managing.Change{LifeTimeSeconds: int64Ptr(0)}},
+		{name: "zero reads left", change: managing.Change{ReadsLeft: intPtr(0)}},
+		{name: "negative reads left", change: managing.Change{ReadsLeft: intPtr(-1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			repository := mockRepository{}
+
+			s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+			// when
+			_, gotErr := s.UpdateNote(context.TODO(), "qx2rx", "mgmt-token", tt.change)
+
+			// then
+			assert.Equal(t, managing.ErrInvalidChange, gotErr)
+			repository.AssertNotCalled(t, "GetNoteStatus", mock.Anything)
+		})
+	}
+}
+
+func TestService_UpdateNoteLifetimeBeyondLimits(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	limits := creating.DefaultLimits
+	limits.MaxLifeTimeSeconds = 3600
+
+	s := managing.NewService(&repository, timer, hashToken, limits)
+
+	// when
+	_, gotErr := s.UpdateNote(context.TODO(), "qx2rx", "mgmt-token", managing.Change{LifeTimeSeconds: int64Ptr(3601)})
+
+	// then
+	var validationErr *creating.ValidationError
+	assert.True(t, errors.As(gotErr, &validationErr), "got %v", gotErr)
+	assert.True(t, errors.Is(gotErr, creating.ErrLifetimeOutOfRange))
+	repository.AssertNotCalled(t, "UpdateNote", mock.Anything, mock.Anything)
+}
+
+func TestService_UpdateNoteNotAuthorized(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	_, gotErr := s.UpdateNote(context.TODO(), "qx2rx", "guess", managing.Change{ReadsLeft: intPtr(100)})
+
+	// then
+	assert.Equal(t, managing.ErrNotAuthorized, gotErr)
+	repository.AssertNotCalled(t, "UpdateNote", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteNoteOK(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+	repository.On("DeleteNote", "qx2rx").Return(nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	gotErr := s.DeleteNote(context.TODO(), "qx2rx", "mgmt-token")
+
+	// then
+	assert.NoError(t, gotErr)
+	repository.AssertExpectations(t)
+}
+
+func TestService_DeleteNoteNotAuthorized(t *testing.T) {
+	// given
+	repository := mockRepository{}
+	repository.On("GetNoteStatus", "qx2rx").Return(managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: "hashed:mgmt-token",
+		TTL:                 time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix(),
+	}, nil)
+
+	s := managing.NewService(&repository, timer, hashToken, creating.DefaultLimits)
+
+	// when
+	gotErr := s.DeleteNote(context.TODO(), "qx2rx", "guess")
+
+	// then
+	assert.Equal(t, managing.ErrNotAuthorized, gotErr)
+	repository.AssertNotCalled(t, "DeleteNote", mock.Anything)
+}
+
+func timer() time.Time {
+	return time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+}
+
+func hashToken(token string) string {
+	return "hashed:" + token
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error) {
+	args := m.Called(noteID)
+	return args.Get(0).(managing.SecureNoteStatus), args.Error(1)
+}
+
+func (m *mockRepository) UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error) {
+	args := m.Called(noteID, u)
+	return args.Get(0).(managing.SecureNoteStatus), args.Error(1)
+}
+
+func (m *mockRepository) DeleteNote(ctx context.Context, noteID string) error {
+	args := m.Called(noteID)
+	return args.Error(0)
+}
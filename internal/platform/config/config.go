@@ -0,0 +1,340 @@
+// Package config loads settings shared by every binary from environment variables
+// and an optional JSON file named by CONFIG_FILE. Environment variables take precedence.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/projects/secure-notes/internal/platform/web"
+)
+
+// Storage backends selectable with STORAGE_BACKEND
+const (
+	BackendDynamoDB = "dynamodb"
+	BackendBolt     = "bolt"
+	BackendMemory   = "memory"
+)
+
+// ID generators selectable with ID_GENERATOR
+const (
+	IDGeneratorSequential = "sequential"
+	IDGeneratorRandom     = "random"
+	IDGeneratorWords      = "words"
+)
+
+// Key managers selectable with KMS_PROVIDER
+const (
+	KMSNone  = "none"
+	KMSLocal = "local"
+	KMSAWS   = "aws"
+)
+
+// Webhook outboxes selectable with WEBHOOK_OUTBOX
+const (
+	OutboxMemory = "memory"
+	OutboxSQS    = "sqs"
+)
+
+const (
+	// minPepperBytes matches the output size of the HMAC keyed with a pepper
+	minPepperBytes = 32
+	// masterKeyBytes selects AES-256 master keys
+	masterKeyBytes = 32
+)
+
+// Config holds settings of every binary
+type Config struct {
+	Storage  Storage
+	KMS      KMS
+	IDs      IDs
+	Limits   creating.Limits
+	Security Security
+	Webhooks Webhooks
+	Web      Web
+	Server   Server
+}
+
+// Storage selects and configures the storage backend
+type Storage struct {
+	Backend       string
+	TableName     string
+	BoltPath      string
+	SweepInterval time.Duration
+}
+
+// KMS selects the key manager used for envelope encryption of stored notes
+type KMS struct {
+	Provider string
+	// KeyID names the master key wrapping new data keys: a key of LocalKeys or an AWS KMS key ID, ARN or alias
+	KeyID string
+	// LocalKeys holds master keys of the local key manager by ID
+	LocalKeys map[string][]byte
+}
+
+// IDs selects and configures generator of note IDs
+type IDs struct {
+	Generator string
+	Salt      string
+	Length    int
+	Alphabet  string
+	Words     int
+}
+
+// Security configures the derivation of keys sealing note text from note passwords
+type Security struct {
+	// Argon2id sets the cost of sealing new notes. Sealed notes record their own parameters.
+	Argon2id security.KDFParams
+
+	// Peppers key HMACs of passwords before the key derivation, by key ID. Empty disables peppering.
+	Peppers map[string][]byte
+	// PepperKeyID names the pepper of new notes. Other peppers only open older notes.
+	PepperKeyID string
+}
+
+// Webhooks selects the outbox of read receipts. The memory outbox loses receipts when
+// the process is frozen or stops, so Lambda deployments need the SQS one.
+type Webhooks struct {
+	Outbox   string
+	QueueURL string
+}
+
+// Web configures the HTTP middleware
+type Web struct {
+	CORS web.CORS
+	// IPHashKey keys hashes of logged source IPs. Empty means a random key per process.
+	IPHashKey string
+}
+
+// Server configures the standalone HTTP server
+type Server struct {
+	Addr        string
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// Error lists every problem found in the configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Load reads configuration from the process environment
+func Load() (Config, error) {
+	return LoadEnv(os.LookupEnv)
+}
+
+// LoadEnv reads configuration from variables provided by lookup and from the file
+// named by CONFIG_FILE, if any. Secrets (ID_SALT, LOG_IP_HASH_KEY, PASSWORD_PEPPERS, KMS_LOCAL_KEYS) can instead be read
+// from a file named by the variable with a _FILE suffix.
+func LoadEnv(lookup func(key string) (string, bool)) (Config, error) {
+	s := &source{env: lookup, readFile: ioutil.ReadFile}
+
+	if path, ok := lookup("CONFIG_FILE"); ok && path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("read config file: %w", err)
+		}
+		if s.file, err = parseFile(b); err != nil {
+			return Config{}, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	c := Config{
+		Storage: Storage{
+			Backend:       s.string("STORAGE_BACKEND", BackendDynamoDB),
+			TableName:     s.string("NOTES_TABLE", ""),
+			BoltPath:      s.string("BOLT_PATH", "notes.db"),
+			SweepInterval: s.duration("SWEEP_INTERVAL", time.Minute),
+		},
+		KMS: KMS{
+			Provider: s.string("KMS_PROVIDER", KMSNone),
+		},
+		IDs: IDs{
+			Generator: s.string("ID_GENERATOR", IDGeneratorRandom),
+			Salt:      s.secret("ID_SALT", ""),
+			Length:    s.int("ID_LENGTH", 12),
+			Alphabet:  s.string("ID_ALPHABET", creating.DefaultIDAlphabet),
+			Words:     s.int("ID_WORDS", 4),
+		},
+		Limits: creating.Limits{
+			MaxTextBytes:       s.int("NOTE_MAX_TEXT_BYTES", creating.DefaultLimits.MaxTextBytes),
+			MaxCiphertextBytes: s.int("NOTE_MAX_CIPHERTEXT_BYTES", creating.DefaultLimits.MaxCiphertextBytes),
+			MaxMetadataBytes:   s.int("NOTE_MAX_METADATA_BYTES", creating.DefaultLimits.MaxMetadataBytes),
+			MinLifeTimeSeconds: s.int64("NOTE_MIN_LIFETIME_SECONDS", creating.DefaultLimits.MinLifeTimeSeconds),
+			MaxLifeTimeSeconds: s.int64("NOTE_MAX_LIFETIME_SECONDS", creating.DefaultLimits.MaxLifeTimeSeconds),
+		},
+		Security: Security{
+			Argon2id: security.KDFParams{
+				Time:    uint32(s.uint("ARGON2_TIME", uint64(security.DefaultKDFParams.Time), 32)),
+				Memory:  uint32(s.uint("ARGON2_MEMORY_KIB", uint64(security.DefaultKDFParams.Memory), 32)),
+				Threads: uint8(s.uint("ARGON2_THREADS", uint64(security.DefaultKDFParams.Threads), 8)),
+			},
+		},
+		Webhooks: Webhooks{
+			Outbox:   s.string("WEBHOOK_OUTBOX", OutboxMemory),
+			QueueURL: s.string("WEBHOOK_QUEUE_URL", ""),
+		},
+		Web: Web{
+			CORS: web.CORS{
+				AllowedOrigins:   s.list("CORS_ALLOWED_ORIGINS", nil),
+				AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodDelete},
+				AllowedHeaders:   s.list("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "Password"}),
+				ExposedHeaders:   []string{web.RequestIDHeader, "Retry-After"},
+				AllowCredentials: s.bool("CORS_ALLOW_CREDENTIALS", false),
+				MaxAge:           s.duration("CORS_MAX_AGE", 10*time.Minute),
+			},
+			IPHashKey: s.secret("LOG_IP_HASH_KEY", ""),
+		},
+		Server: Server{
+			Addr:        s.string("SERVER_ADDR", ":8080"),
+			TLSCertFile: s.string("TLS_CERT_FILE", ""),
+			TLSKeyFile:  s.string("TLS_KEY_FILE", ""),
+		},
+	}
+
+	var kmsKeyIDs []string
+	c.KMS.LocalKeys, kmsKeyIDs = s.keys("KMS_LOCAL_KEYS")
+	c.KMS.KeyID = s.string("KMS_KEY_ID", "")
+	if c.KMS.KeyID == "" && len(kmsKeyIDs) > 0 {
+		c.KMS.KeyID = kmsKeyIDs[0]
+	}
+
+	var pepperIDs []string
+	c.Security.Peppers, pepperIDs = s.keys("PASSWORD_PEPPERS")
+	if len(pepperIDs) > 0 {
+		c.Security.PepperKeyID = s.string("PASSWORD_PEPPER_ID", pepperIDs[0])
+	}
+
+	c.validate(s)
+	if len(s.problems) > 0 {
+		return Config{}, &Error{Problems: s.problems}
+	}
+	return c, nil
+}
+
+func (c Config) validate(s *source) {
+	switch c.Storage.Backend {
+	case BackendDynamoDB:
+		if c.Storage.TableName == "" {
+			s.problem("NOTES_TABLE", "is required by the %s backend", BackendDynamoDB)
+		}
+	case BackendBolt, BackendMemory:
+	default:
+		s.problem("STORAGE_BACKEND", "unknown backend %q", c.Storage.Backend)
+	}
+	if c.Storage.SweepInterval <= 0 {
+		s.problem("SWEEP_INTERVAL", "must be positive")
+	}
+
+	switch c.KMS.Provider {
+	case KMSNone:
+	case KMSLocal:
+		if len(c.KMS.LocalKeys) == 0 {
+			s.problem("KMS_LOCAL_KEYS", "is required by the %s key manager", KMSLocal)
+		} else if _, ok := c.KMS.LocalKeys[c.KMS.KeyID]; !ok {
+			s.problem("KMS_KEY_ID", "names no key of KMS_LOCAL_KEYS, got %q", c.KMS.KeyID)
+		}
+		for id, key := range c.KMS.LocalKeys {
+			if len(key) != masterKeyBytes {
+				s.problem("KMS_LOCAL_KEYS", "key %q must have %d bytes", id, masterKeyBytes)
+			}
+		}
+	case KMSAWS:
+		if c.KMS.KeyID == "" {
+			s.problem("KMS_KEY_ID", "is required by the %s key manager", KMSAWS)
+		}
+	default:
+		s.problem("KMS_PROVIDER", "unknown key manager %q", c.KMS.Provider)
+	}
+	if c.KMS.Provider != KMSNone && c.KMS.Provider != "" && c.Storage.Backend != BackendDynamoDB {
+		s.problem("KMS_PROVIDER", "envelope encryption is only supported by the %s backend", BackendDynamoDB)
+	}
+
+	switch c.IDs.Generator {
+	case IDGeneratorSequential:
+		// sequential IDs are only as hard to enumerate as the salt is to guess
+		if c.IDs.Salt == "" {
+			s.problem("ID_SALT", "is required by %s IDs", IDGeneratorSequential)
+		} else if c.IDs.Salt == creating.DefaultIDSalt {
+			s.problem("ID_SALT", "must not be the public default salt")
+		}
+	case IDGeneratorRandom:
+		if c.IDs.Length < 4 || c.IDs.Length > 64 {
+			s.problem("ID_LENGTH", "must be between 4 and 64, got %d", c.IDs.Length)
+		}
+		if len(c.IDs.Alphabet) < 2 {
+			s.problem("ID_ALPHABET", "needs at least 2 characters")
+		}
+	case IDGeneratorWords:
+		if c.IDs.Words < 2 || c.IDs.Words > 16 {
+			s.problem("ID_WORDS", "must be between 2 and 16, got %d", c.IDs.Words)
+		}
+	default:
+		s.problem("ID_GENERATOR", "unknown generator %q", c.IDs.Generator)
+	}
+
+	if c.Limits.MaxTextBytes < 1 {
+		s.problem("NOTE_MAX_TEXT_BYTES", "must be positive")
+	}
+	if c.Limits.MaxCiphertextBytes < 1 {
+		s.problem("NOTE_MAX_CIPHERTEXT_BYTES", "must be positive")
+	}
+	if c.Limits.MaxMetadataBytes < 0 {
+		s.problem("NOTE_MAX_METADATA_BYTES", "must not be negative")
+	}
+	if c.Limits.MinLifeTimeSeconds < 1 {
+		s.problem("NOTE_MIN_LIFETIME_SECONDS", "must be positive")
+	}
+	if c.Limits.MaxLifeTimeSeconds < c.Limits.MinLifeTimeSeconds {
+		s.problem("NOTE_MAX_LIFETIME_SECONDS", "must not be less than NOTE_MIN_LIFETIME_SECONDS")
+	}
+
+	if _, ok := c.Security.Peppers[c.Security.PepperKeyID]; c.Security.PepperKeyID != "" && !ok {
+		s.problem("PASSWORD_PEPPER_ID", "names no key of PASSWORD_PEPPERS, got %q", c.Security.PepperKeyID)
+	}
+	for id, key := range c.Security.Peppers {
+		if len(key) < minPepperBytes {
+			s.problem("PASSWORD_PEPPERS", "key %q must have at least %d bytes", id, minPepperBytes)
+		}
+	}
+	if t := c.Security.Argon2id.Time; t < 1 || t > 16 {
+		s.problem("ARGON2_TIME", "must be between 1 and 16, got %d", t)
+	}
+	if m := c.Security.Argon2id.Memory; m < 8*1024 || m > 1024*1024 {
+		s.problem("ARGON2_MEMORY_KIB", "must be between 8192 and 1048576, got %d", m)
+	}
+	if p := c.Security.Argon2id.Threads; p < 1 || p > 16 {
+		s.problem("ARGON2_THREADS", "must be between 1 and 16, got %d", p)
+	}
+
+	switch c.Webhooks.Outbox {
+	case OutboxMemory:
+	case OutboxSQS:
+		if c.Webhooks.QueueURL == "" {
+			s.problem("WEBHOOK_QUEUE_URL", "is required by the %s outbox", OutboxSQS)
+		}
+	default:
+		s.problem("WEBHOOK_OUTBOX", "unknown outbox %q", c.Webhooks.Outbox)
+	}
+
+	if c.Web.CORS.AllowCredentials && c.Web.CORS.AllowsAnyOrigin() {
+		s.problem("CORS_ALLOW_CREDENTIALS", `must not be set while CORS_ALLOWED_ORIGINS has "*"`)
+	}
+	if c.Web.CORS.MaxAge < 0 {
+		s.problem("CORS_MAX_AGE", "must not be negative")
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		s.problem("TLS_CERT_FILE", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+}
@@ -0,0 +1,312 @@
+package config_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func tempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "config")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func TestLoadEnv_Defaults(t *testing.T) {
+	// when
+	c, err := config.LoadEnv(env(map[string]string{"NOTES_TABLE": "notes"}))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, config.Storage{Backend: config.BackendDynamoDB, TableName: "notes", BoltPath: "notes.db", SweepInterval: time.Minute}, c.Storage)
+	assert.Equal(t, config.IDGeneratorRandom, c.IDs.Generator)
+	assert.Empty(t, c.IDs.Salt)
+	assert.Equal(t, creating.DefaultLimits, c.Limits)
+	assert.Equal(t, security.DefaultKDFParams, c.Security.Argon2id)
+	assert.Empty(t, c.Web.CORS.AllowedOrigins, "no origin may call the API unless listed")
+	assert.Equal(t, ":8080", c.Server.Addr)
+}
+
+func TestLoadEnv_FileAndEnv(t *testing.T) {
+	// given
+	file := tempFile(t, `{
+		"STORAGE_BACKEND": "bolt",
+		"BOLT_PATH": "/var/lib/notes.db",
+		"NOTE_MAX_LIFETIME_SECONDS": 3600,
+		"CORS_ALLOWED_ORIGINS": ["https://notes.example.com", "https://*.example.org"],
+		"CORS_ALLOW_CREDENTIALS": true,
+		"ARGON2_TIME": 8
+	}`)
+
+	// when
+	c, err := config.LoadEnv(env(map[string]string{
+		"CONFIG_FILE": file,
+		"ARGON2_TIME": "4",
+	}))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, config.BackendBolt, c.Storage.Backend)
+	assert.Equal(t, "/var/lib/notes.db", c.Storage.BoltPath)
+	assert.Equal(t, int64(3600), c.Limits.MaxLifeTimeSeconds)
+	assert.Equal(t, []string{"https://notes.example.com", "https://*.example.org"}, c.Web.CORS.AllowedOrigins)
+	assert.True(t, c.Web.CORS.AllowCredentials)
+	assert.Equal(t, uint32(4), c.Security.Argon2id.Time, "environment overrides file")
+}
+
+func TestLoadEnv_SecretFromFile(t *testing.T) {
+	// given
+	salt := tempFile(t, "very secret salt\n")
+
+	// when
+	c, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND": "memory",
+		"ID_SALT_FILE":    salt,
+	}))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "very secret salt", c.IDs.Salt)
+}
+
+func TestLoadEnv_ReportsAllProblems(t *testing.T) {
+	// when
+	_, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND":           "dynamodb",
+		"SWEEP_INTERVAL":            "often",
+		"ID_GENERATOR":              "random",
+		"ID_LENGTH":                 "2",
+		"NOTE_MIN_LIFETIME_SECONDS": "60",
+		"NOTE_MAX_LIFETIME_SECONDS": "30",
+		"ARGON2_TIME":               "64",
+		"ARGON2_THREADS":            "300",
+		"CORS_ALLOW_CREDENTIALS":    "maybe",
+		"TLS_KEY_FILE":              "key.pem",
+		"WEBHOOK_OUTBOX":            "sqs",
+		"LOG_IP_HASH_KEY":           "key",
+		"LOG_IP_HASH_KEY_FILE":      "key.txt",
+	}))
+
+	// then
+	var configErr *config.Error
+	require.True(t, errors.As(err, &configErr), "got %v", err)
+	assert.ElementsMatch(t, []string{
+		`SWEEP_INTERVAL: "often" is not a duration`,
+		`CORS_ALLOW_CREDENTIALS: "maybe" is not a boolean`,
+		`LOG_IP_HASH_KEY: set both LOG_IP_HASH_KEY and LOG_IP_HASH_KEY_FILE`,
+		`NOTES_TABLE: is required by the dynamodb backend`,
+		`ID_LENGTH: must be between 4 and 64, got 2`,
+		`NOTE_MAX_LIFETIME_SECONDS: must not be less than NOTE_MIN_LIFETIME_SECONDS`,
+		`ARGON2_TIME: must be between 1 and 16, got 64`,
+		`ARGON2_THREADS: "300" is not an integer between 0 and 255`,
+		`TLS_CERT_FILE: TLS_CERT_FILE and TLS_KEY_FILE must be set together`,
+		`WEBHOOK_QUEUE_URL: is required by the sqs outbox`,
+	}, configErr.Problems)
+}
+
+func TestLoadEnv_CredentialsNeedListedOrigins(t *testing.T) {
+	// when
+	_, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND":        "memory",
+		"CORS_ALLOWED_ORIGINS":   "https://notes.example.com,*",
+		"CORS_ALLOW_CREDENTIALS": "true",
+	}))
+
+	// then
+	var configErr *config.Error
+	require.True(t, errors.As(err, &configErr), "got %v", err)
+	assert.Equal(t, []string{`CORS_ALLOW_CREDENTIALS: must not be set while CORS_ALLOWED_ORIGINS has "*"`}, configErr.Problems)
+}
+
+func TestLoadEnv_SequentialIDsNeedSecretSalt(t *testing.T) {
+	tests := []struct {
+		name string
+		salt map[string]string
+		want string
+	}{
+		{name: "missing", salt: map[string]string{}, want: "ID_SALT: is required by sequential IDs"},
+		{name: "public default", salt: map[string]string{"ID_SALT": creating.DefaultIDSalt}, want: "ID_SALT: must not be the public default salt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			vars := map[string]string{"STORAGE_BACKEND": "memory", "ID_GENERATOR": "sequential"}
+			for k, v := range tt.salt {
+				vars[k] = v
+			}
+
+			// when
+			_, err := config.LoadEnv(env(vars))
+
+			// then
+			var configErr *config.Error
+			require.True(t, errors.As(err, &configErr), "got %v", err)
+			assert.Equal(t, []string{tt.want}, configErr.Problems)
+		})
+	}
+}
+
+func TestLoadEnv_MissingFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{
+			name: "config file",
+			vars: map[string]string{"CONFIG_FILE": "/nonexistent/config.json"},
+		},
+		{
+			name: "secret file",
+			vars: map[string]string{"STORAGE_BACKEND": "memory", "ID_SALT_FILE": "/nonexistent/salt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			_, err := config.LoadEnv(env(tt.vars))
+
+			// then
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadEnv_Peppers(t *testing.T) {
+	// given
+	peppers := tempFile(t, "2020-03:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=,2020-06:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=\n")
+
+	// when
+	c, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND":       "memory",
+		"PASSWORD_PEPPERS_FILE": peppers,
+		"PASSWORD_PEPPER_ID":    "2020-06",
+	}))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"2020-03": []byte("0123456789abcdef0123456789abcdef"),
+		"2020-06": []byte("fedcba9876543210fedcba9876543210"),
+	}, c.Security.Peppers)
+	assert.Equal(t, "2020-06", c.Security.PepperKeyID)
+}
+
+func TestLoadEnv_PepperDefaultsToFirstKey(t *testing.T) {
+	// when
+	c, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND":  "memory",
+		"PASSWORD_PEPPERS": "k2:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=, k1:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=",
+	}))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "k2", c.Security.PepperKeyID)
+}
+
+func TestLoadEnv_InvalidPeppers(t *testing.T) {
+	// when
+	_, err := config.LoadEnv(env(map[string]string{
+		"STORAGE_BACKEND":    "memory",
+		"PASSWORD_PEPPERS":   "short:c2hvcnQ=,bad$id:c2hvcnQ=,k1:not base64",
+		"PASSWORD_PEPPER_ID": "k9",
+	}))
+
+	// then
+	var configErr *config.Error
+	require.True(t, errors.As(err, &configErr), "got %v", err)
+	assert.ElementsMatch(t, []string{
+		`PASSWORD_PEPPERS: entries must look like <id>:<base64 key> with IDs of letters, digits, - and _`,
+		`PASSWORD_PEPPERS: key "k1" is not valid base64`,
+		`PASSWORD_PEPPER_ID: names no key of PASSWORD_PEPPERS, got "k9"`,
+		`PASSWORD_PEPPERS: key "short" must have at least 32 bytes`,
+	}, configErr.Problems)
+}
+
+func TestLoadEnv_KMS(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    config.KMS
+		wantErr []string
+	}{
+		{
+			name: "disabled by default",
+			vars: map[string]string{"NOTES_TABLE": "notes"},
+			want: config.KMS{Provider: config.KMSNone},
+		},
+		{
+			name: "local keys",
+			vars: map[string]string{
+				"NOTES_TABLE":    "notes",
+				"KMS_PROVIDER":   "local",
+				"KMS_LOCAL_KEYS": "m1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+			},
+			want: config.KMS{
+				Provider:  config.KMSLocal,
+				KeyID:     "m1",
+				LocalKeys: map[string][]byte{"m1": []byte("0123456789abcdef0123456789abcdef")},
+			},
+		},
+		{
+			name: "aws key",
+			vars: map[string]string{"NOTES_TABLE": "notes", "KMS_PROVIDER": "aws", "KMS_KEY_ID": "alias/notes"},
+			want: config.KMS{Provider: config.KMSAWS, KeyID: "alias/notes"},
+		},
+		{
+			name:    "aws without key",
+			vars:    map[string]string{"NOTES_TABLE": "notes", "KMS_PROVIDER": "aws"},
+			wantErr: []string{`KMS_KEY_ID: is required by the aws key manager`},
+		},
+		{
+			name: "local with other backend",
+			vars: map[string]string{
+				"STORAGE_BACKEND": "bolt",
+				"KMS_PROVIDER":    "local",
+				"KMS_LOCAL_KEYS":  "m1:c2hvcnQ=",
+			},
+			wantErr: []string{
+				`KMS_LOCAL_KEYS: key "m1" must have 32 bytes`,
+				`KMS_PROVIDER: envelope encryption is only supported by the dynamodb backend`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			c, err := config.LoadEnv(env(tt.vars))
+
+			// then
+			if tt.wantErr != nil {
+				var configErr *config.Error
+				require.True(t, errors.As(err, &configErr), "got %v", err)
+				assert.ElementsMatch(t, tt.wantErr, configErr.Problems)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.KMS)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// source looks settings up by their environment variable names, first in the environment
+// and then in the config file. Malformed values are recorded as problems and replaced by
+// defaults, so that one load reports all of them.
+type source struct {
+	env      func(key string) (string, bool)
+	file     map[string]string
+	readFile func(path string) ([]byte, error)
+	problems []string
+}
+
+func (s *source) problem(key, format string, args ...interface{}) {
+	s.problems = append(s.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (s *source) lookup(key string) (string, bool) {
+	if v, ok := s.env(key); ok && v != "" {
+		return v, true
+	}
+	v, ok := s.file[key]
+	return v, ok && v != ""
+}
+
+func (s *source) string(key, def string) string {
+	if v, ok := s.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+// secret reads key or, when <key>_FILE is set instead, the contents of the file it names.
+// Trailing newlines are dropped, as editors and secret stores tend to add them.
+func (s *source) secret(key, def string) string {
+	path, fromFile := s.lookup(key + "_FILE")
+	v, ok := s.lookup(key)
+	switch {
+	case fromFile && ok:
+		s.problem(key, "set both %s and %s_FILE", key, key)
+		return def
+	case fromFile:
+		b, err := s.readFile(path)
+		if err != nil {
+			s.problem(key+"_FILE", "%v", err)
+			return def
+		}
+		return strings.TrimRight(string(b), "\r\n")
+	case ok:
+		return v
+	}
+	return def
+}
+
+func (s *source) int(key string, def int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.problem(key, "%q is not an integer", v)
+		return def
+	}
+	return n
+}
+
+func (s *source) int64(key string, def int64) int64 {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		s.problem(key, "%q is not an integer", v)
+		return def
+	}
+	return n
+}
+
+// uint reads an unsigned integer that fits into bits
+func (s *source) uint(key string, def uint64, bits int) uint64 {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.ParseUint(v, 10, bits)
+	if err != nil {
+		s.problem(key, "%q is not an integer between 0 and %d", v, uint64(1)<<bits-1)
+		return def
+	}
+	return n
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.problem(key, "%q is not a boolean", v)
+		return def
+	}
+	return b
+}
+
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		s.problem(key, "%q is not a duration", v)
+		return def
+	}
+	return d
+}
+
+// list reads comma separated values
+func (s *source) list(key string, def []string) []string {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return def
+	}
+	return items
+}
+
+// keys reads a secret of comma separated <id>:<base64 key> pairs and returns them with
+// the IDs in the order listed
+func (s *source) keys(key string) (map[string][]byte, []string) {
+	v := s.secret(key, "")
+	if v == "" {
+		return nil, nil
+	}
+
+	keys := make(map[string][]byte)
+	var ids []string
+	for _, pair := range strings.Split(v, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || !validKeyID(parts[0]) {
+			s.problem(key, "entries must look like <id>:<base64 key> with IDs of letters, digits, - and _")
+			continue
+		}
+		id := parts[0]
+		k, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			s.problem(key, "key %q is not valid base64", id)
+			continue
+		}
+		if _, ok := keys[id]; ok {
+			s.problem(key, "key %q is listed twice", id)
+			continue
+		}
+		keys[id] = k
+		ids = append(ids, id)
+	}
+	return keys, ids
+}
+
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFile reads a JSON object keyed by environment variable names. Values may be strings,
+// numbers, booleans or, for lists, arrays of strings.
+func parseFile(b []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			settings[key] = v
+		case json.Number:
+			settings[key] = v.String()
+		case bool:
+			settings[key] = strconv.FormatBool(v)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: list items must be strings", key)
+				}
+				items[i] = s
+			}
+			settings[key] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("%s: unsupported value %v", key, value)
+		}
+	}
+	return settings, nil
+}
@@ -0,0 +1,73 @@
+package kms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// AWS wraps data keys with a customer master key in AWS KMS. Master keys never leave KMS,
+// and rewrapping happens inside it as well.
+type AWS struct {
+	client *awskms.Client
+	keyID  string
+}
+
+// NewAWS provides a key manager wrapping new data keys with the KMS key keyID,
+// given as key ID, key ARN or alias
+func NewAWS(client *awskms.Client, keyID string) *AWS {
+	return &AWS{client: client, keyID: keyID}
+}
+
+func (a *AWS) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	input := awskms.GenerateDataKeyInput{
+		KeyId:   aws.String(a.keyID),
+		KeySpec: awskms.DataKeySpecAes256,
+	}
+
+	resp, err := a.client.GenerateDataKeyRequest(&input).Send(ctx)
+	if err != nil {
+		return DataKey{}, fmt.Errorf("kms generate data key: %w", err)
+	}
+
+	return DataKey{
+		Plaintext: resp.Plaintext,
+		Wrapped: WrappedKey{
+			KeyID:      aws.StringValue(resp.KeyId),
+			Ciphertext: resp.CiphertextBlob,
+		},
+	}, nil
+}
+
+func (a *AWS) Decrypt(ctx context.Context, wrapped WrappedKey) ([]byte, error) {
+	input := awskms.DecryptInput{
+		CiphertextBlob: wrapped.Ciphertext,
+		KeyId:          aws.String(wrapped.KeyID),
+	}
+
+	resp, err := a.client.DecryptRequest(&input).Send(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+func (a *AWS) ReEncrypt(ctx context.Context, wrapped WrappedKey) (WrappedKey, error) {
+	input := awskms.ReEncryptInput{
+		CiphertextBlob:   wrapped.Ciphertext,
+		SourceKeyId:      aws.String(wrapped.KeyID),
+		DestinationKeyId: aws.String(a.keyID),
+	}
+
+	resp, err := a.client.ReEncryptRequest(&input).Send(ctx)
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("kms re-encrypt: %w", err)
+	}
+
+	return WrappedKey{
+		KeyID:      aws.StringValue(resp.KeyId),
+		Ciphertext: resp.CiphertextBlob,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package kms_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/defaults"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/projects/secure-notes/internal/platform/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS answers the AWS KMS JSON API using a local key manager
+func fakeKMS(t *testing.T, local *kms.Local) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KeyId            string
+			SourceKeyId      string
+			DestinationKeyId string
+			CiphertextBlob   []byte
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var resp interface{}
+		switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "TrentService.") {
+		case "GenerateDataKey":
+			key, err := local.GenerateDataKey(r.Context())
+			require.NoError(t, err)
+			resp = map[string]interface{}{"KeyId": key.Wrapped.KeyID, "CiphertextBlob": key.Wrapped.Ciphertext, "Plaintext": key.Plaintext}
+		case "Decrypt":
+			key, err := local.Decrypt(r.Context(), kms.WrappedKey{KeyID: req.KeyId, Ciphertext: req.CiphertextBlob})
+			require.NoError(t, err)
+			resp = map[string]interface{}{"KeyId": req.KeyId, "Plaintext": key}
+		case "ReEncrypt":
+			assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/2", req.DestinationKeyId)
+			wrapped, err := local.ReEncrypt(r.Context(), kms.WrappedKey{KeyID: req.SourceKeyId, Ciphertext: req.CiphertextBlob})
+			require.NoError(t, err)
+			resp = map[string]interface{}{"KeyId": wrapped.KeyID, "CiphertextBlob": wrapped.Ciphertext}
+		default:
+			http.Error(w, "unexpected target", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+func awsClient(url string) *awskms.Client {
+	cfg := defaults.Config()
+	cfg.Region = "us-east-1"
+	cfg.Credentials = aws.NewStaticCredentialsProvider("AKID", "SECRET", "")
+	cfg.EndpointResolver = aws.ResolveWithEndpointURL(url)
+	return awskms.New(cfg)
+}
+
+func TestAWS_SealOpenAndReEncrypt(t *testing.T) {
+	// given
+	local, err := kms.NewLocal(map[string][]byte{
+		"arn:aws:kms:us-east-1:123456789012:key/1": k1,
+		"arn:aws:kms:us-east-1:123456789012:key/2": k2,
+	}, "arn:aws:kms:us-east-1:123456789012:key/1")
+	require.NoError(t, err)
+	srv := fakeKMS(t, local)
+	defer srv.Close()
+
+	km := kms.NewAWS(awsClient(srv.URL), "arn:aws:kms:us-east-1:123456789012:key/2")
+
+	// when
+	e, sealErr := kms.Seal(context.TODO(), km, []byte("Hello World"), []byte("qx2rx"))
+	got, openErr := kms.Open(context.TODO(), km, e, []byte("qx2rx"))
+	rewrapped, reEncryptErr := km.ReEncrypt(context.TODO(), e.Key)
+
+	// then
+	assert.NoError(t, sealErr)
+	assert.NoError(t, openErr)
+	assert.NoError(t, reEncryptErr)
+	assert.Equal(t, "Hello World", string(got))
+	assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/1", e.Key.KeyID)
+	assert.NotEqual(t, e.Key.Ciphertext, rewrapped.Ciphertext)
+}
@@ -0,0 +1,107 @@
+// Package kms provides envelope encryption: data is encrypted with a fresh data key,
+// which is stored next to it wrapped by a master key that never leaves the key manager.
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// dataKeyLength selects AES-256 data keys
+const dataKeyLength = 32
+
+var (
+	// ErrUnknownKey is used when a wrapped key names a master key the manager does not have.
+	ErrUnknownKey = errors.New("unknown master key")
+
+	// ErrDecrypt is used when a wrapped key or envelope cannot be decrypted.
+	ErrDecrypt = errors.New("cannot decrypt")
+)
+
+// KeyManager generates data keys and unwraps them with master keys it holds
+type KeyManager interface {
+	// GenerateDataKey returns a new data key in plaintext and wrapped by the current master key
+	GenerateDataKey(ctx context.Context) (DataKey, error)
+	// Decrypt unwraps a data key
+	Decrypt(ctx context.Context, wrapped WrappedKey) ([]byte, error)
+	// ReEncrypt wraps a data key by the current master key, so older master keys can be retired
+	ReEncrypt(ctx context.Context, wrapped WrappedKey) (WrappedKey, error)
+}
+
+// WrappedKey is a data key encrypted by the master key with KeyID
+type WrappedKey struct {
+	KeyID      string
+	Ciphertext []byte
+}
+
+// DataKey is a data key together with its wrapped form to be stored
+type DataKey struct {
+	Plaintext []byte
+	Wrapped   WrappedKey
+}
+
+// Envelope is data encrypted with AES-256-GCM under a data key stored wrapped next to it
+type Envelope struct {
+	Key        WrappedKey
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Seal encrypts plaintext under a new data key. Additional data, such as the ID of the record
+// holding the envelope, is authenticated, so envelopes cannot be moved between records.
+func Seal(ctx context.Context, km KeyManager, plaintext, additionalData []byte) (Envelope, error) {
+	key, err := km.GenerateDataKey(ctx)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("generate data key: %w", err)
+	}
+
+	aead, err := newAEAD(key.Plaintext)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return Envelope{}, fmt.Errorf("read random nonce: %w", err)
+	}
+
+	return Envelope{
+		Key:        key.Wrapped,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, additionalData),
+	}, nil
+}
+
+// Open decrypts an envelope made by Seal with the same additional data
+func Open(ctx context.Context, km KeyManager, e Envelope, additionalData []byte) ([]byte, error) {
+	key, err := km.Decrypt(ctx, e.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,117 @@
+package kms_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	k1 = bytes.Repeat([]byte{1}, 32)
+	k2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestSealAndOpen(t *testing.T) {
+	// given
+	km, err := kms.NewLocal(map[string][]byte{"k1": k1}, "k1")
+	require.NoError(t, err)
+
+	// when
+	e, err := kms.Seal(context.TODO(), km, []byte("Hello World"), []byte("qx2rx"))
+	require.NoError(t, err)
+	got, openErr := kms.Open(context.TODO(), km, e, []byte("qx2rx"))
+
+	// then
+	assert.NoError(t, openErr)
+	assert.Equal(t, "Hello World", string(got))
+	assert.Equal(t, "k1", e.Key.KeyID)
+	assert.NotContains(t, string(e.Ciphertext), "Hello World")
+}
+
+func TestOpen_Tampered(t *testing.T) {
+	km, err := kms.NewLocal(map[string][]byte{"k1": k1, "k2": k2}, "k1")
+	require.NoError(t, err)
+	e, err := kms.Seal(context.TODO(), km, []byte("Hello World"), []byte("qx2rx"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		envelope       func() kms.Envelope
+		additionalData string
+	}{
+		{
+			name:           "other record",
+			envelope:       func() kms.Envelope { return e },
+			additionalData: "k7QbX",
+		},
+		{
+			name: "ciphertext",
+			envelope: func() kms.Envelope {
+				tampered := e
+				tampered.Ciphertext = append([]byte{e.Ciphertext[0] ^ 1}, e.Ciphertext[1:]...)
+				return tampered
+			},
+			additionalData: "qx2rx",
+		},
+		{
+			name: "relabelled master key",
+			envelope: func() kms.Envelope {
+				tampered := e
+				tampered.Key.KeyID = "k2"
+				return tampered
+			},
+			additionalData: "qx2rx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			_, err := kms.Open(context.TODO(), km, tt.envelope(), []byte(tt.additionalData))
+
+			// then
+			assert.True(t, errors.Is(err, kms.ErrDecrypt), "got %v", err)
+		})
+	}
+}
+
+func TestLocal_ReEncrypt(t *testing.T) {
+	// given
+	before, err := kms.NewLocal(map[string][]byte{"k1": k1}, "k1")
+	require.NoError(t, err)
+	after, err := kms.NewLocal(map[string][]byte{"k1": k1, "k2": k2}, "k2")
+	require.NoError(t, err)
+	retired, err := kms.NewLocal(map[string][]byte{"k2": k2}, "k2")
+	require.NoError(t, err)
+
+	e, err := kms.Seal(context.TODO(), before, []byte("Hello World"), nil)
+	require.NoError(t, err)
+
+	// when
+	rewrapped, err := after.ReEncrypt(context.TODO(), e.Key)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rewrapped.KeyID)
+
+	_, err = kms.Open(context.TODO(), retired, e, nil)
+	assert.True(t, errors.Is(err, kms.ErrUnknownKey), "got %v", err)
+
+	e.Key = rewrapped
+	got, err := kms.Open(context.TODO(), retired, e, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello World", string(got), "rewrapping must keep the data key")
+}
+
+func TestNewLocal_InvalidKeys(t *testing.T) {
+	_, shortErr := kms.NewLocal(map[string][]byte{"k1": []byte("short")}, "k1")
+	_, unknownErr := kms.NewLocal(map[string][]byte{"k1": k1}, "k2")
+
+	assert.Error(t, shortErr)
+	assert.True(t, errors.Is(unknownErr, kms.ErrUnknownKey), "got %v", unknownErr)
+}
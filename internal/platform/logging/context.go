@@ -0,0 +1,39 @@
+// Package logging carries the request ID and a logger tagged with it through context.Context,
+// so every layer handling a request logs lines that can be correlated.
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+)
+
+var nop = zap.NewNop().Sugar()
+
+// WithRequestID returns ctx carrying the request ID and a logger derived from logger tagged with it
+func WithRequestID(ctx context.Context, logger *zap.SugaredLogger, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return context.WithValue(ctx, loggerKey, logger.With("requestId", requestID))
+}
+
+// RequestID returns the ID of the request handled with ctx or "" outside of requests
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// FromContext returns the logger of the request handled with ctx.
+// Outside of requests it returns a logger discarding everything.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return nop
+}
@@ -0,0 +1,37 @@
+package logging_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/logging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_FromContextTagsRequestID(t *testing.T) {
+	// given
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logging.WithRequestID(context.TODO(), zap.New(core).Sugar(), "c6af9ac6-7b61-11e6-9a41-93e8deadbeef")
+
+	// when
+	logging.FromContext(ctx).Infow("note created", "noteId", "qx2rx")
+
+	// then
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", logging.RequestID(ctx))
+	assert.Equal(t, map[string]interface{}{
+		"requestId": "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+		"noteId":    "qx2rx",
+	}, logs.All()[0].ContextMap())
+}
+
+func Test_FromContextOutsideOfRequest(t *testing.T) {
+	// when
+	logger := logging.FromContext(context.TODO())
+
+	// then
+	assert.NotNil(t, logger)
+	assert.Equal(t, "", logging.RequestID(context.TODO()))
+}
@@ -1,36 +1,85 @@
 package provider
 
 import (
+	"crypto/rand"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/external"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/kms"
 	"github.com/projects/secure-notes/internal/platform/web"
 	db "github.com/projects/secure-notes/internal/storage/dynamodb"
 	"go.uber.org/zap"
 )
 
-func AWSConfig() aws.Config {
+func AWSConfig() (aws.Config, error) {
 	cfg, err := external.LoadDefaultAWSConfig()
 	if err != nil {
-		panic("cannot read AWS configuration")
+		return aws.Config{}, fmt.Errorf("read AWS configuration: %w", err)
 	}
 
-	return cfg
+	return cfg, nil
 }
 
-func DynamoStorage(cfg aws.Config, tableName string) *db.Storage {
+func DynamoStorage(cfg aws.Config, tableName string, keys kms.KeyManager) *db.Storage {
 	dbCli := dynamodb.New(cfg)
-	storage := db.NewStorage(dbCli, tableName)
+	storage := db.NewStorage(dbCli, tableName, keys)
 	return storage
 }
 
-func Middleware() *web.Middleware {
+// Logger provides the logger shared by requests and background work, so all output has one format
+func Logger() (*zap.SugaredLogger, error) {
 	logger, err := zap.NewProductionConfig().Build()
 	if err != nil {
-		panic("cannot initialize logger")
+		return nil, fmt.Errorf("initialize logger: %w", err)
+	}
+	return logger.Sugar(), nil
+}
+
+// KeyManager provides the configured key manager for envelope encryption, or nil if it is disabled
+func KeyManager(c config.KMS, cfg aws.Config) (kms.KeyManager, error) {
+	switch c.Provider {
+	case config.KMSLocal:
+		keys, err := kms.NewLocal(c.LocalKeys, c.KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("create local key manager: %w", err)
+		}
+		return keys, nil
+	case config.KMSAWS:
+		return kms.NewAWS(awskms.New(cfg), c.KeyID), nil
+	default:
+		return nil, nil
+	}
+}
+
+func Middleware(c config.Web, logger *zap.SugaredLogger) (*web.Middleware, error) {
+	key, err := ipHashKey(c.IPHashKey)
+	if err != nil {
+		return nil, err
+	}
+	if c.CORS.AllowsAnyOrigin() {
+		logger.Warnw("CORS allows any origin, list origins in CORS_ALLOWED_ORIGINS outside development")
 	}
 	middleware := web.Middleware{
-		Logger: logger.Sugar(),
+		Logger:    logger,
+		IPHashKey: key,
+		CORS:      c.CORS,
+	}
+	return &middleware, nil
+}
+
+// ipHashKey uses the configured key, so hashed IPs correlate across instances.
+// Without it a random per-process key still keeps logged IPs unrecoverable.
+func ipHashKey(configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate IP hash key: %w", err)
 	}
-	return &middleware
+	return key, nil
 }
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/config"
+)
+
+// IDGenerator provides the configured note ID generator.
+// Only sequential IDs use the note counter of storage.
+func IDGenerator(c config.IDs, storage NoteStorage) (creating.IDGenerator, error) {
+	var (
+		genID creating.IDGenerator
+		err   error
+	)
+
+	switch c.Generator {
+	case config.IDGeneratorSequential:
+		genID = creating.SequentialIDs(storage, c.Salt)
+	case config.IDGeneratorRandom:
+		genID, err = creating.RandomIDs(c.Length, c.Alphabet)
+	case config.IDGeneratorWords:
+		genID, err = creating.WordIDs(c.Words, creating.DefaultWords, "-")
+	default:
+		err = fmt.Errorf("unknown ID generator %q", c.Generator)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("create ID generator: %w", err)
+	}
+	return genID, nil
+}
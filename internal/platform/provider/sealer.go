@@ -0,0 +1,12 @@
+package provider
+
+import (
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/security"
+)
+
+// Sealer seals new note text with the configured KDF cost and pepper and opens text sealed
+// with any supported parameters and configured pepper, so changes keep existing notes readable
+func Sealer(c config.Security) security.Sealer {
+	return security.Sealer{Params: c.Argon2id, Peppers: c.Peppers, PepperKeyID: c.PepperKeyID}
+}
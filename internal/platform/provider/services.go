@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/projects/secure-notes/internal/platform/webhook"
+	"go.uber.org/zap"
+)
+
+// Services holds every note service wired to the configured storage
+type Services struct {
+	Creator *creating.Service
+	Getter  *getting.Service
+	Manager *managing.Service
+	// Outbox delivers read receipts of Getter and should be closed before exiting
+	Outbox ReceiptOutbox
+
+	closeStorage func() error
+}
+
+// NewServices provides note services of the whole API, so binaries serving it share their wiring
+func NewServices(cfg config.Config, logger *zap.SugaredLogger) (*Services, error) {
+	storage, closeStorage, err := Storage(cfg.Storage, cfg.KMS, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	now := func() time.Time { return time.Now().UTC() }
+	genID, err := IDGenerator(cfg.IDs, storage)
+	if err != nil {
+		return nil, err
+	}
+	sealer := Sealer(cfg.Security)
+	outbox, err := Outbox(cfg.Webhooks, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Services{
+		Creator: creating.NewService(storage, now, sealer.SealText, genID, security.GenerateToken, security.HashToken, cfg.Limits),
+		Getter:  getting.NewService(storage, now, sealer.OpenText, outbox),
+		Manager: managing.NewService(storage, now, security.HashToken, cfg.Limits),
+		Outbox:  outbox,
+
+		closeStorage: closeStorage,
+	}, nil
+}
+
+// Close delivers read receipts still queued until ctx is done and then releases the storage.
+// It is called once the services no longer serve requests.
+func (s *Services) Close(ctx context.Context) error {
+	outboxErr := s.Outbox.Close(ctx)
+	if err := s.closeStorage(); err != nil {
+		return fmt.Errorf("close storage: %w", err)
+	}
+	if outboxErr != nil {
+		return fmt.Errorf("drain webhook outbox: %w", outboxErr)
+	}
+	return nil
+}
+
+// Handler loads the configuration and wraps the handler that choose picks from the services with
+// CORS and logging, so Lambda binaries differ only in the routes they serve. Lambda freezes functions
+// between requests and never closes the outbox, so they rely on the sqs outbox for read receipts,
+// and every request waits for receipts it sent to SQS in the background before responding.
+func Handler(choose func(s *Services) web.Handler) (web.Handler, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	logger, err := Logger()
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := NewServices(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	middleware, err := Middleware(cfg.Web, logger)
+	if err != nil {
+		return nil, err
+	}
+	handler := choose(services)
+	if queue, ok := services.Outbox.(*webhook.Queue); ok {
+		handler = flushing(handler, queue)
+	}
+	return middleware.WrapWithCorsAndLogging(handler), nil
+}
+
+// flushing waits for read receipts sent by h before its response is returned and the function frozen
+func flushing(h web.Handler, queue *webhook.Queue) web.Handler {
+	return func(ctx context.Context, req web.Request) (web.Response, error) {
+		resp, err := h(ctx, req)
+		_ = queue.Flush(ctx)
+		return resp, err
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/storage"
+	"github.com/projects/secure-notes/internal/storage/bolt"
+	"github.com/projects/secure-notes/internal/storage/memory"
+	"go.uber.org/zap"
+)
+
+// NoteStorage is implemented by every storage backend
+type NoteStorage interface {
+	CreateNote(ctx context.Context, sn creating.SecureNote) error
+	IncrementNoteCounter(ctx context.Context) (int, error)
+	GetNote(ctx context.Context, noteID string) (getting.SecureNote, error)
+	GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error)
+	ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error)
+	RecordRead(ctx context.Context, noteID string, at time.Time) error
+	DeleteNote(ctx context.Context, noteID string) error
+	ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error)
+	ClearFailedAttempts(ctx context.Context, noteID string) error
+	GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error)
+	UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error)
+}
+
+// Storage provides the configured storage backend and a function releasing it, to be called once
+// no request uses the storage anymore. Backends without native TTL support get a background
+// sweeper deleting expired notes, which runs until the storage is released.
+func Storage(c config.Storage, k config.KMS, logger *zap.SugaredLogger) (NoteStorage, func() error, error) {
+	now := func() time.Time { return time.Now().UTC() }
+
+	switch c.Backend {
+	case config.BackendDynamoDB:
+		cfg, err := AWSConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		keys, err := KeyManager(k, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return DynamoStorage(cfg, c.TableName, keys), func() error { return nil }, nil
+
+	case config.BackendBolt:
+		s, err := bolt.Open(c.BoltPath, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open bolt storage: %w", err)
+		}
+		stop := startSweeper(s, c.SweepInterval, logger)
+		return s, func() error {
+			// the sweeper is stopped first, as it must not delete from a closed database
+			stop()
+			return s.Close()
+		}, nil
+
+	case config.BackendMemory:
+		s := memory.NewStorage(now)
+		stop := startSweeper(s, c.SweepInterval, logger)
+		return s, func() error {
+			stop()
+			return nil
+		}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", c.Backend)
+	}
+}
+
+// startSweeper runs a sweeper in the background and returns a function stopping it,
+// which returns once a sweep in progress has finished
+func startSweeper(d storage.ExpiredDeleter, interval time.Duration, logger *zap.SugaredLogger) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		storage.RunSweeper(ctx, d, interval, func(err error) {
+			logger.Warnw("sweep expired notes", "error", err)
+		})
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/projects/secure-notes/internal/platform/config"
+	"github.com/projects/secure-notes/internal/platform/webhook"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookTimeout = 5 * time.Second
+	// queueTimeout bounds sending a read receipt to SQS, which Lambda waits for before responding
+	queueTimeout = time.Second
+)
+
+// ReceiptOutbox queues read receipts for delivery and is closed before exiting
+type ReceiptOutbox interface {
+	Enqueue(url, secret string, event interface{}) error
+	Close(ctx context.Context) error
+}
+
+// Outbox provides the configured outbox of webhook read receipts. Failed deliveries are only logged,
+// since readers must not be affected by webhooks of note creators.
+func Outbox(c config.Webhooks, logger *zap.SugaredLogger) (ReceiptOutbox, error) {
+	switch c.Outbox {
+	case config.OutboxSQS:
+		cfg, err := AWSConfig()
+		if err != nil {
+			return nil, err
+		}
+		return webhook.NewQueue(sqs.New(cfg), c.QueueURL, queueTimeout, func(err error) {
+			logger.Warnw("enqueue read receipt", "error", err)
+		}), nil
+
+	case config.OutboxMemory:
+		return webhook.NewOutbox(webhook.NewClient(webhookTimeout), webhook.Config{
+			OnError: webhookErrorLogger(logger),
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown webhook outbox %q", c.Outbox)
+	}
+}
+
+// WebhookReceiver provides the Lambda handler delivering read receipts queued in SQS
+func WebhookReceiver(logger *zap.SugaredLogger) func(ctx context.Context, e events.SQSEvent) error {
+	return webhook.Receive(webhook.NewClient(webhookTimeout), webhookErrorLogger(logger))
+}
+
+func webhookErrorLogger(logger *zap.SugaredLogger) func(error) {
+	return func(err error) {
+		logger.Warnw("deliver webhook", "error", err)
+	}
+}
@@ -0,0 +1,32 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownPepper is used when sealed text names a pepper key that is not configured.
+var ErrUnknownPepper = errors.New("unknown pepper key")
+
+// peppered keys password with the pepper named by sealed text, if any
+func (s Sealer) peppered(sealed SealedText, password string) (string, error) {
+	if sealed.PepperKeyID == "" {
+		return password, nil
+	}
+
+	key, ok := s.Peppers[sealed.PepperKeyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownPepper, sealed.PepperKeyID)
+	}
+	return pepper(key, password), nil
+}
+
+// pepper encodes the HMAC-SHA256 of password as text, which the KDF then takes as the password
+func pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
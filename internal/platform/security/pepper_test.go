@@ -0,0 +1,77 @@
+package security_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldPepper = []byte("0123456789abcdef0123456789abcdef")
+	newPepper = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestSealer_PepperedSealAndOpen(t *testing.T) {
+	// given
+	s := security.Sealer{Params: fastKDF, Peppers: map[string][]byte{"k1": oldPepper}, PepperKeyID: "k1"}
+	sealed, err := s.SealText("Hello World", "abc")
+	require.NoError(t, err)
+
+	// when
+	gotText, gotErr := s.OpenText(sealed, "abc")
+	_, wrongErr := s.OpenText(sealed, "abd")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "Hello World", gotText)
+	assert.Equal(t, "k1", sealed.PepperKeyID)
+	assert.Equal(t, security.ErrDecrypt, wrongErr)
+}
+
+func TestSealer_SealedTextUselessWithoutPepper(t *testing.T) {
+	// given
+	s := security.Sealer{Params: fastKDF, Peppers: map[string][]byte{"k1": oldPepper}, PepperKeyID: "k1"}
+	sealed, err := s.SealText("Hello World", "abc")
+	require.NoError(t, err)
+	leaked := sealed
+	leaked.PepperKeyID = ""
+
+	// when
+	_, namedErr := security.OpenText(sealed, "abc")
+	gotText, leakedErr := security.OpenText(leaked, "abc")
+
+	// then
+	assert.True(t, errors.Is(namedErr, security.ErrUnknownPepper), "got %v", namedErr)
+	assert.Equal(t, security.ErrDecrypt, leakedErr, "a leaked note must not open with the bare password")
+	assert.Equal(t, "", gotText)
+}
+
+func TestSealer_PepperRotation(t *testing.T) {
+	// given
+	before := security.Sealer{Params: fastKDF, Peppers: map[string][]byte{"k1": oldPepper}, PepperKeyID: "k1"}
+	after := security.Sealer{Params: fastKDF, Peppers: map[string][]byte{"k1": oldPepper, "k2": newPepper}, PepperKeyID: "k2"}
+	retired := security.Sealer{Params: fastKDF, Peppers: map[string][]byte{"k2": newPepper}, PepperKeyID: "k2"}
+	oldSealed, err := before.SealText("Hello World", "abc")
+	require.NoError(t, err)
+	unpeppered, err := security.Sealer{Params: fastKDF}.SealText("Hello World", "abc")
+	require.NoError(t, err)
+
+	// when
+	gotText, openErr := after.OpenText(oldSealed, "abc")
+	_, retiredErr := retired.OpenText(oldSealed, "abc")
+	legacyText, legacyErr := after.OpenText(unpeppered, "abc")
+
+	// then
+	assert.NoError(t, openErr)
+	assert.Equal(t, "Hello World", gotText, "text sealed with an old pepper opens while it is configured")
+	assert.True(t, errors.Is(retiredErr, security.ErrUnknownPepper), "got %v", retiredErr)
+	assert.NoError(t, legacyErr)
+	assert.Equal(t, "Hello World", legacyText)
+
+	newSealed, err := after.SealText("Hello World", "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", newSealed.PepperKeyID)
+}
@@ -0,0 +1,174 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Identifiers of algorithms used to seal a note. They are persisted next to
+// every sealed text, so the scheme can evolve without breaking older notes.
+const (
+	CipherAES256GCM = "aes-256-gcm"
+	KDFArgon2id     = "argon2id"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+var (
+	// ErrDecrypt is used when sealed text cannot be opened with given password.
+	ErrDecrypt = errors.New("cannot decrypt sealed text")
+
+	// ErrUnsupportedAlgorithm is used when sealed text names unknown cipher or KDF.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+)
+
+// KDFParams defines cost parameters of the key derivation function
+type KDFParams struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// DefaultKDFParams are used for newly sealed notes (RFC 9106, second recommended option)
+var DefaultKDFParams = KDFParams{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// SealedText defines text encrypted with a key derived from a password
+type SealedText struct {
+	Cipher    string
+	KDF       string
+	KDFParams KDFParams
+	// PepperKeyID names the pepper the password was keyed with, or is empty for text sealed without pepper
+	PepperKeyID string
+	Salt        []byte
+	Nonce       []byte
+	Ciphertext  []byte
+}
+
+// SealText encrypts text with AES-256-GCM under a key derived from password with Argon2id and a random salt
+func SealText(text, password string) (SealedText, error) {
+	return Sealer{Params: DefaultKDFParams}.SealText(text, password)
+}
+
+// OpenText decrypts sealed text using algorithms and parameters recorded in it.
+// Text sealed with a pepper can only be opened by a Sealer holding that pepper.
+func OpenText(sealed SealedText, password string) (string, error) {
+	return Sealer{}.OpenText(sealed, password)
+}
+
+// Sealer seals text under a key derived from its password with Params. As the key authenticates
+// the text, opening it is the only check of a password and no weaker verifier is stored next to it.
+// With Peppers set, passwords are keyed with the pepper named by PepperKeyID before the derivation,
+// so a leaked table alone is not enough to open notes by guessing their passwords. The pepper ID is
+// recorded in the sealed text, so peppers can be rotated: text sealed with older peppers opens as
+// long as they are kept, and so does text sealed before peppering was enabled.
+type Sealer struct {
+	Params      KDFParams
+	Peppers     map[string][]byte
+	PepperKeyID string
+}
+
+func (s Sealer) SealText(text, password string) (SealedText, error) {
+	sealed := SealedText{
+		Cipher:      CipherAES256GCM,
+		KDF:         KDFArgon2id,
+		KDFParams:   s.Params,
+		PepperKeyID: s.PepperKeyID,
+	}
+	password, err := s.peppered(sealed, password)
+	if err != nil {
+		return SealedText{}, err
+	}
+
+	sealed.Salt = make([]byte, saltLength)
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return SealedText{}, fmt.Errorf("read random salt: %w", err)
+	}
+
+	key, err := deriveKey(sealed, password)
+	if err != nil {
+		return SealedText{}, fmt.Errorf("derive key: %w", err)
+	}
+
+	aead, err := newAEAD(sealed.Cipher, key)
+	if err != nil {
+		return SealedText{}, fmt.Errorf("create cipher: %w", err)
+	}
+
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return SealedText{}, fmt.Errorf("read random nonce: %w", err)
+	}
+
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, []byte(text), nil)
+	return sealed, nil
+}
+
+// OpenText fails with ErrDecrypt when password is wrong
+func (s Sealer) OpenText(sealed SealedText, password string) (string, error) {
+	password, err := s.peppered(sealed, password)
+	if err != nil {
+		return "", err
+	}
+	return openText(sealed, password)
+}
+
+func openText(sealed SealedText, password string) (string, error) {
+	key, err := deriveKey(sealed, password)
+	if err != nil {
+		return "", fmt.Errorf("derive key: %w", err)
+	}
+
+	aead, err := newAEAD(sealed.Cipher, key)
+	if err != nil {
+		return "", fmt.Errorf("create cipher: %w", err)
+	}
+
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+
+	plain, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+
+	return string(plain), nil
+}
+
+func deriveKey(sealed SealedText, password string) ([]byte, error) {
+	switch sealed.KDF {
+	case KDFArgon2id:
+		p := sealed.KDFParams
+		if p.Time == 0 || p.Threads == 0 {
+			return nil, fmt.Errorf("invalid argon2id params %+v", p)
+		}
+		return argon2.IDKey([]byte(password), sealed.Salt, p.Time, p.Memory, p.Threads, keyLength), nil
+	default:
+		return nil, fmt.Errorf("kdf %q: %w", sealed.KDF, ErrUnsupportedAlgorithm)
+	}
+}
+
+func newAEAD(cipherName string, key []byte) (cipher.AEAD, error) {
+	switch cipherName {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	default:
+		return nil, fmt.Errorf("cipher %q: %w", cipherName, ErrUnsupportedAlgorithm)
+	}
+}
@@ -0,0 +1,100 @@
+package security_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/stretchr/testify/assert"
+)
+
+// fastKDF keeps tests quick, real deployments use security.DefaultKDFParams
+var fastKDF = security.KDFParams{Time: 1, Memory: 64, Threads: 1}
+
+func Test_SealAndOpenText(t *testing.T) {
+	// given
+	sealed, err := security.SealText("Hello World", "abc")
+	assert.NoError(t, err)
+
+	// when
+	gotText, gotErr := security.OpenText(sealed, "abc")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, "Hello World", gotText)
+	assert.Equal(t, security.CipherAES256GCM, sealed.Cipher)
+	assert.Equal(t, security.KDFArgon2id, sealed.KDF)
+	assert.Equal(t, security.DefaultKDFParams, sealed.KDFParams)
+	assert.NotContains(t, string(sealed.Ciphertext), "Hello World")
+}
+
+func Test_SealTextUsesRandomSaltAndNonce(t *testing.T) {
+	// when
+	first, firstErr := security.SealText("Hello World", "abc")
+	second, secondErr := security.SealText("Hello World", "abc")
+
+	// then
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.NotEqual(t, first.Salt, second.Salt)
+	assert.NotEqual(t, first.Nonce, second.Nonce)
+	assert.NotEqual(t, first.Ciphertext, second.Ciphertext)
+}
+
+func Test_OpenTextWrongPassword(t *testing.T) {
+	// given
+	sealed, err := security.SealText("Hello World", "abc")
+	assert.NoError(t, err)
+
+	// when
+	gotText, gotErr := security.OpenText(sealed, "wrongpassword")
+
+	// then
+	assert.Equal(t, security.ErrDecrypt, gotErr)
+	assert.Equal(t, "", gotText)
+}
+
+func Test_OpenTextUnsupportedCipher(t *testing.T) {
+	// given
+	sealed, err := security.SealText("Hello World", "abc")
+	assert.NoError(t, err)
+	sealed.Cipher = "rot13"
+
+	// when
+	_, gotErr := security.OpenText(sealed, "abc")
+
+	// then
+	assert.True(t, errors.Is(gotErr, security.ErrUnsupportedAlgorithm))
+}
+
+func TestSealer_RecordsKDFParams(t *testing.T) {
+	// given
+	s := security.Sealer{Params: fastKDF}
+
+	// when
+	sealed, err := s.SealText("Hello World", "abc")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, fastKDF, sealed.KDFParams)
+	gotText, err := security.OpenText(sealed, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello World", gotText)
+}
+
+// BenchmarkSealer helps to pick KDF parameters for the CPU share of the Lambda memory size,
+// as opening a note costs one key derivation
+func BenchmarkSealer(b *testing.B) {
+	s := security.Sealer{Params: security.DefaultKDFParams}
+	sealed, err := s.SealText("Hello World", "correct horse battery staple")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.OpenText(sealed, "correct horse battery staple"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
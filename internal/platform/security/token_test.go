@@ -0,0 +1,29 @@
+package security_test
+
+import (
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GenerateToken(t *testing.T) {
+	// when
+	gotFirst, firstErr := security.GenerateToken()
+	gotSecond, secondErr := security.GenerateToken()
+
+	// then
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.NotEqual(t, gotFirst, gotSecond)
+	assert.Len(t, gotFirst, 43)
+}
+
+func Test_HashToken(t *testing.T) {
+	// when
+	gotHash := security.HashToken("abc")
+
+	// then
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", gotHash)
+	assert.NotEqual(t, gotHash, security.HashToken("abd"))
+}
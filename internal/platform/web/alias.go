@@ -1,8 +0,0 @@
-package web
-
-import "github.com/aws/aws-lambda-go/events"
-
-type (
-	Request  = events.APIGatewayProxyRequest
-	Response = events.APIGatewayProxyResponse
-)
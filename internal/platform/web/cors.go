@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS decides which browser origins may call the API
+type CORS struct {
+	// AllowedOrigins holds exact origins ("https://notes.example.com"), wildcard subdomains
+	// ("https://*.example.com") or "*" for any origin
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies. Only listed origins are then allowed,
+	// as "*" would hand credentialed responses to every site.
+	AllowCredentials bool
+	// MaxAge tells browsers how long to cache preflight results
+	MaxAge time.Duration
+}
+
+// Handler answers preflight requests itself and adds CORS headers to other responses of h.
+// Headers set by h are kept; only requests from allowed origins get Access-Control-Allow-Origin.
+func (c CORS) Handler(h Handler) Handler {
+	return func(ctx context.Context, req Request) (Response, error) {
+		origin := req.Headers.Get("Origin")
+
+		if req.Method == http.MethodOptions && req.Headers.Get("Access-Control-Request-Method") != "" {
+			resp := Response{StatusCode: http.StatusNoContent, Headers: make(http.Header)}
+			if c.addOrigin(resp.Headers, origin) {
+				resp.Headers.Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+				resp.Headers.Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+				if c.MaxAge > 0 {
+					resp.Headers.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+				}
+			}
+			return resp, nil
+		}
+
+		resp, err := h(ctx, req)
+		if resp.Headers == nil {
+			resp.Headers = make(http.Header)
+		}
+		if c.addOrigin(resp.Headers, origin) && len(c.ExposedHeaders) > 0 {
+			resp.Headers.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		return resp, err
+	}
+}
+
+// addOrigin sets headers allowing origin and tells whether it is allowed
+func (c CORS) addOrigin(headers http.Header, origin string) bool {
+	if c.AllowsAnyOrigin() && !c.AllowCredentials {
+		headers.Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+
+	// the response depends on the origin, so caches must not share it between origins
+	headers.Set("Vary", "Origin")
+	if origin == "" || !c.allows(origin) {
+		return false
+	}
+	headers.Set("Access-Control-Allow-Origin", origin)
+	if c.AllowCredentials {
+		headers.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// AllowsAnyOrigin tells whether "*" is among the allowed origins
+func (c CORS) AllowsAnyOrigin() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allows matches origin against the listed origins. "*" is skipped, so an origin is never
+// echoed back with credentials just because any origin is allowed.
+func (c CORS) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == origin {
+			return true
+		}
+		if matchesWildcard(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWildcard matches "https://*.example.com" against subdomains of example.com of any depth,
+// but neither against example.com itself nor against lookalikes such as "https://evilexample.com".
+func matchesWildcard(pattern, origin string) bool {
+	i := strings.Index(pattern, "://*.")
+	if i < 0 {
+		return false
+	}
+	scheme, domain := pattern[:i+3], pattern[i+4:]
+	if !strings.HasPrefix(origin, scheme) {
+		return false
+	}
+	host := strings.TrimPrefix(origin, scheme)
+	return strings.HasSuffix(host, domain) && len(host) > len(domain)
+}
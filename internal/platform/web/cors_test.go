@@ -0,0 +1,168 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+)
+
+var cors = web.CORS{
+	AllowedOrigins: []string{"https://notes.example.com", "https://*.preview.example.com"},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost},
+	AllowedHeaders: []string{"Content-Type", "Password"},
+	ExposedHeaders: []string{web.RequestIDHeader},
+	MaxAge:         10 * time.Minute,
+}
+
+func Test_CORSEchoesOnlyAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		wantOrigin string
+	}{
+		{name: "exact origin", origin: "https://notes.example.com", wantOrigin: "https://notes.example.com"},
+		{name: "wildcard subdomain", origin: "https://pr-12.preview.example.com", wantOrigin: "https://pr-12.preview.example.com"},
+		{name: "nested wildcard subdomain", origin: "https://a.pr-12.preview.example.com", wantOrigin: "https://a.pr-12.preview.example.com"},
+		{name: "wildcard parent domain", origin: "https://preview.example.com"},
+		{name: "lookalike domain", origin: "https://evilpreview.example.com"},
+		{name: "other scheme", origin: "http://notes.example.com"},
+		{name: "other origin", origin: "https://evil.com"},
+		{name: "no origin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+				return web.Response{StatusCode: http.StatusOK, Headers: http.Header{"Content-Type": {"application/json"}}}, nil
+			}
+
+			req := web.Request{Method: http.MethodGet, Headers: http.Header{"Origin": {tt.origin}}}
+
+			// when
+			gotResp, _ := cors.Handler(handler)(context.TODO(), req)
+
+			// then
+			assert.Equal(t, tt.wantOrigin, gotResp.Headers.Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "Origin", gotResp.Headers.Get("Vary"))
+			assert.Equal(t, "application/json", gotResp.Headers.Get("Content-Type"))
+			assert.NotContains(t, gotResp.Headers, "Access-Control-Allow-Credentials")
+			if tt.wantOrigin != "" {
+				assert.Equal(t, web.RequestIDHeader, gotResp.Headers.Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func Test_CORSAnswersPreflight(t *testing.T) {
+	// given
+	called := false
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		called = true
+		return web.Response{StatusCode: http.StatusMethodNotAllowed}, nil
+	}
+
+	req := web.Request{
+		Method: http.MethodOptions,
+		Headers: http.Header{
+			"Origin":                         {"https://notes.example.com"},
+			"Access-Control-Request-Method":  {http.MethodGet},
+			"Access-Control-Request-Headers": {"password"},
+		},
+	}
+
+	// when
+	gotResp, gotErr := cors.Handler(handler)(context.TODO(), req)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.False(t, called)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusNoContent,
+		Headers: http.Header{
+			"Access-Control-Allow-Origin":  {"https://notes.example.com"},
+			"Access-Control-Allow-Methods": {"GET, POST"},
+			"Access-Control-Allow-Headers": {"Content-Type, Password"},
+			"Access-Control-Max-Age":       {"600"},
+			"Vary":                         {"Origin"},
+		},
+	}, gotResp)
+}
+
+func Test_CORSPreflightFromOtherOrigin(t *testing.T) {
+	// given
+	req := web.Request{
+		Method: http.MethodOptions,
+		Headers: http.Header{
+			"Origin":                        {"https://evil.com"},
+			"Access-Control-Request-Method": {http.MethodGet},
+		},
+	}
+
+	// when
+	gotResp, _ := cors.Handler(nil)(context.TODO(), req)
+
+	// then
+	assert.Equal(t, http.StatusNoContent, gotResp.StatusCode)
+	assert.NotContains(t, gotResp.Headers, "Access-Control-Allow-Origin")
+	assert.NotContains(t, gotResp.Headers, "Access-Control-Allow-Headers")
+}
+
+func Test_CORSAnyOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials bool
+		wantOrigin  string
+	}{
+		{name: "without credentials", wantOrigin: "*"},
+		{name: "with credentials", credentials: true, wantOrigin: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			c := web.CORS{AllowedOrigins: []string{"*"}, AllowCredentials: tt.credentials}
+			handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+				return web.Response{StatusCode: http.StatusOK}, nil
+			}
+			req := web.Request{Headers: http.Header{"Origin": {"https://notes.example.com"}}}
+
+			// when
+			gotResp, _ := c.Handler(handler)(context.TODO(), req)
+
+			// then
+			assert.Equal(t, tt.wantOrigin, gotResp.Headers.Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func Test_CORSCredentialsIgnoreAnyOrigin(t *testing.T) {
+	tests := []struct {
+		origin     string
+		wantOrigin string
+	}{
+		{origin: "https://notes.example.com", wantOrigin: "https://notes.example.com"},
+		{origin: "https://evil.com", wantOrigin: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			// given
+			c := web.CORS{AllowedOrigins: []string{"*", "https://notes.example.com"}, AllowCredentials: true}
+			handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+				return web.Response{StatusCode: http.StatusOK}, nil
+			}
+			req := web.Request{Headers: http.Header{"Origin": {tt.origin}}}
+
+			// when
+			gotResp, _ := c.Handler(handler)(context.TODO(), req)
+
+			// then
+			assert.Equal(t, tt.wantOrigin, gotResp.Headers.Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrorMapping tells which problem response answers errors matching Err
+type ErrorMapping struct {
+	Err    error
+	Status int
+	Code   string
+	// Detail is shown to clients, so it must not be taken from wrapped errors
+	Detail string
+}
+
+// MapErrors answers errors returned by h without a response status using the first
+// matching mapping, or with 500 when none matches. Handlers can then return domain
+// errors as they are and leave choosing status codes to one place.
+func MapErrors(h Handler, mappings ...ErrorMapping) Handler {
+	return func(ctx context.Context, req Request) (Response, error) {
+		resp, err := h(ctx, req)
+		if err == nil || resp.StatusCode != 0 {
+			return resp, err
+		}
+
+		for _, m := range mappings {
+			if errors.Is(err, m.Err) {
+				return ProblemResponse(req, m.Status, m.Code, Problem{Detail: m.Detail}), err
+			}
+		}
+		return InternalServerError(req), err
+	}
+}
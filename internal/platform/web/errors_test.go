@@ -0,0 +1,66 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+)
+
+var errMissing = errors.New("missing")
+
+func Test_MapErrors(t *testing.T) {
+	mappings := []web.ErrorMapping{
+		{Err: errMissing, Status: http.StatusNotFound, Code: web.CodeNoteNotFound},
+	}
+
+	tests := []struct {
+		name       string
+		resp       web.Response
+		err        error
+		wantStatus int
+	}{
+		{name: "no error", resp: web.Response{StatusCode: http.StatusOK}, wantStatus: http.StatusOK},
+		{name: "wrapped sentinel", err: fmt.Errorf("get note: %w", errMissing), wantStatus: http.StatusNotFound},
+		{name: "unknown error", err: errors.New("some db error"), wantStatus: http.StatusInternalServerError},
+		{name: "error with response", resp: web.Response{StatusCode: http.StatusTooManyRequests}, err: errMissing, wantStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+				return tt.resp, tt.err
+			}
+
+			// when
+			gotResp, gotErr := web.MapErrors(handler, mappings...)(context.TODO(), web.Request{})
+
+			// then
+			assert.Equal(t, tt.wantStatus, gotResp.StatusCode)
+			assert.Equal(t, tt.err, gotErr)
+		})
+	}
+}
+
+func Test_MapErrorsUsesOnlyMappedDetail(t *testing.T) {
+	// given
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		return web.Response{}, fmt.Errorf("update note in table secret-notes: %w", errMissing)
+	}
+
+	// when
+	gotResp, _ := web.MapErrors(handler, web.ErrorMapping{
+		Err:    errMissing,
+		Status: http.StatusBadRequest,
+		Code:   web.CodeValidationFailed,
+		Detail: "change is invalid",
+	})(context.TODO(), web.Request{})
+
+	// then
+	assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"change is invalid","code":"validation_failed"}`, gotResp.Body)
+}
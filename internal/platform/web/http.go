@@ -0,0 +1,72 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// MaxBodyBytes limits size of request bodies accepted by the net/http adapter.
+// API Gateway enforces its own payload limit of 10 MB.
+const MaxBodyBytes = 10 << 20
+
+// HTTPHandler adapts h to net/http, so the same handlers and middleware serve
+// both Lambda and standalone deployments. pathParams extracts path parameters
+// from the request and may be nil.
+func HTTPHandler(h Handler, pathParams func(r *http.Request) map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if pathParams != nil {
+			params = pathParams(r)
+		}
+
+		req, err := ToRequest(r, params)
+		if err != nil {
+			WriteResponse(w, Error(req, http.StatusRequestEntityTooLarge, CodeRequestTooLarge))
+			return
+		}
+
+		resp, _ := h(r.Context(), req)
+		WriteResponse(w, resp)
+	})
+}
+
+// ToRequest converts net/http request into Request
+func ToRequest(r *http.Request, pathParams map[string]string) (Request, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodyBytes))
+	if err != nil {
+		return Request{}, fmt.Errorf("read request body: %w", err)
+	}
+
+	return Request{
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		Headers:        r.Header.Clone(),
+		Query:          r.URL.Query(),
+		PathParameters: pathParams,
+		Body:           string(body),
+		SourceIP:       sourceIP(r),
+	}, nil
+}
+
+// WriteResponse writes Response to w
+func WriteResponse(w http.ResponseWriter, resp Response) {
+	for name, values := range resp.Headers {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+
+	w.WriteHeader(statusCode(resp))
+	io.WriteString(w, resp.Body)
+}
+
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,71 @@
+package web_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HTTPHandlerConvertsRequestAndResponse(t *testing.T) {
+	// given
+	var gotReq web.Request
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		gotReq = req
+		return web.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": {"application/json"}},
+			Body:       `{"id":"qx2rx"}`,
+		}, nil
+	}
+	pathParams := func(r *http.Request) map[string]string {
+		return map[string]string{"id": "qx2rx"}
+	}
+
+	srv := httptest.NewServer(web.HTTPHandler(handler, pathParams))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/notes?x=1", strings.NewReader(`{"text":"Hello World"}`))
+	req.Header.Set("Password", "mySecretPassword")
+
+	// when
+	resp, err := http.DefaultClient.Do(req)
+
+	// then
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"id":"qx2rx"}`, string(body))
+
+	assert.Equal(t, http.MethodPost, gotReq.Method)
+	assert.Equal(t, "/notes", gotReq.Path)
+	assert.Equal(t, "mySecretPassword", gotReq.Headers.Get("password"))
+	assert.Equal(t, "1", gotReq.Query.Get("x"))
+	assert.Equal(t, "qx2rx", gotReq.PathParameters["id"])
+	assert.Equal(t, `{"text":"Hello World"}`, gotReq.Body)
+	assert.Equal(t, "127.0.0.1", gotReq.SourceIP)
+}
+
+func Test_WriteResponseKeepsRepeatedHeaders(t *testing.T) {
+	// given
+	rec := httptest.NewRecorder()
+
+	// when
+	web.WriteResponse(rec, web.Response{
+		Headers: http.Header{"Set-Cookie": {"a=1", "b=2"}},
+		Body:    "Hello World",
+	})
+
+	// then
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"a=1", "b=2"}, rec.Header().Values("Set-Cookie"))
+	assert.Equal(t, "Hello World", rec.Body.String())
+}
@@ -0,0 +1,254 @@
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// LambdaHandler adapts h to Lambda, telling REST API, HTTP API and ALB events apart by their
+// shape, so the same function can be deployed behind any of them
+func LambdaHandler(h Handler) func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	v1, v2, alb := APIGatewayV1(h), APIGatewayV2(h), ALB(h)
+
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		var probe struct {
+			Version        string `json:"version"`
+			RequestContext struct {
+				ELB json.RawMessage `json:"elb"`
+			} `json:"requestContext"`
+		}
+		if err := json.Unmarshal(event, &probe); err != nil {
+			return nil, fmt.Errorf("unmarshal event: %w", err)
+		}
+
+		switch {
+		case probe.Version == "2.0":
+			var e APIGatewayV2Request
+			if err := json.Unmarshal(event, &e); err != nil {
+				return nil, fmt.Errorf("unmarshal http api event: %w", err)
+			}
+			return v2(ctx, e)
+		case probe.RequestContext.ELB != nil:
+			var e ALBRequest
+			if err := json.Unmarshal(event, &e); err != nil {
+				return nil, fmt.Errorf("unmarshal alb event: %w", err)
+			}
+			return alb(ctx, e)
+		default:
+			var e APIGatewayV1Request
+			if err := json.Unmarshal(event, &e); err != nil {
+				return nil, fmt.Errorf("unmarshal rest api event: %w", err)
+			}
+			return v1(ctx, e)
+		}
+	}
+}
+
+// APIGatewayV1 adapts h to REST API proxy events
+func APIGatewayV1(h Handler) func(ctx context.Context, e APIGatewayV1Request) (APIGatewayV1Response, error) {
+	return func(ctx context.Context, e APIGatewayV1Request) (APIGatewayV1Response, error) {
+		req := Request{
+			Method:         e.HTTPMethod,
+			Path:           e.Path,
+			Route:          e.Resource,
+			Headers:        headersFrom(e.Headers, e.MultiValueHeaders),
+			Query:          queryFrom(e.QueryStringParameters, e.MultiValueQueryStringParameters, false),
+			PathParameters: e.PathParameters,
+			RequestID:      e.RequestContext.RequestID,
+			SourceIP:       e.RequestContext.Identity.SourceIP,
+		}
+
+		resp := serve(ctx, h, req, e.Body, e.IsBase64Encoded)
+		body, isBase64 := encodeBody(resp.Body)
+		return APIGatewayV1Response{
+			StatusCode:        statusCode(resp),
+			MultiValueHeaders: resp.Headers,
+			Body:              body,
+			IsBase64Encoded:   isBase64,
+		}, nil
+	}
+}
+
+// APIGatewayV2 adapts h to HTTP API events
+func APIGatewayV2(h Handler) func(ctx context.Context, e APIGatewayV2Request) (APIGatewayV2Response, error) {
+	return func(ctx context.Context, e APIGatewayV2Request) (APIGatewayV2Response, error) {
+		headers := headersFrom(e.Headers, nil)
+		if len(e.Cookies) > 0 {
+			headers.Set("Cookie", strings.Join(e.Cookies, "; "))
+		}
+		query, err := url.ParseQuery(e.RawQueryString)
+		if err != nil {
+			query = queryFrom(e.QueryStringParameters, nil, false)
+		}
+
+		req := Request{
+			Method:         e.RequestContext.HTTP.Method,
+			Path:           e.RawPath,
+			Route:          routeOf(e.RouteKey),
+			Headers:        headers,
+			Query:          query,
+			PathParameters: e.PathParameters,
+			RequestID:      e.RequestContext.RequestID,
+			SourceIP:       e.RequestContext.HTTP.SourceIP,
+		}
+
+		resp := serve(ctx, h, req, e.Body, e.IsBase64Encoded)
+		body, isBase64 := encodeBody(resp.Body)
+		return APIGatewayV2Response{
+			StatusCode:      statusCode(resp),
+			Headers:         singleHeaders(resp.Headers),
+			Cookies:         resp.Headers.Values("Set-Cookie"),
+			Body:            body,
+			IsBase64Encoded: isBase64,
+		}, nil
+	}
+}
+
+// ALB adapts h to Application Load Balancer events. Without multi-value headers enabled
+// on the target group, a response can set only its first cookie.
+func ALB(h Handler) func(ctx context.Context, e ALBRequest) (ALBResponse, error) {
+	return func(ctx context.Context, e ALBRequest) (ALBResponse, error) {
+		headers := headersFrom(e.Headers, e.MultiValueHeaders)
+		req := Request{
+			Method:   e.HTTPMethod,
+			Path:     e.Path,
+			Headers:  headers,
+			Query:    queryFrom(e.QueryStringParameters, e.MultiValueQueryStringParameters, true),
+			SourceIP: clientIP(headers.Get("X-Forwarded-For")),
+		}
+
+		resp := serve(ctx, h, req, e.Body, e.IsBase64Encoded)
+		body, isBase64 := encodeBody(resp.Body)
+		out := ALBResponse{
+			StatusCode:        statusCode(resp),
+			StatusDescription: fmt.Sprintf("%d %s", statusCode(resp), http.StatusText(statusCode(resp))),
+			Body:              body,
+			IsBase64Encoded:   isBase64,
+		}
+		// the response must use the header form of the request
+		if e.MultiValueHeaders != nil {
+			out.MultiValueHeaders = resp.Headers
+		} else {
+			out.Headers = singleHeaders(resp.Headers)
+			if cookie := resp.Headers.Get("Set-Cookie"); cookie != "" {
+				out.Headers["Set-Cookie"] = cookie
+			}
+		}
+		return out, nil
+	}
+}
+
+// serve decodes the event body into req and answers it with h. Errors of h are not returned,
+// as Lambda would turn them into a bare 502 instead of the response h made.
+func serve(ctx context.Context, h Handler, req Request, body string, isBase64 bool) Response {
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return Error(req, http.StatusBadRequest, CodeMalformedRequest)
+		}
+		body = string(decoded)
+	}
+	req.Body = body
+
+	resp, _ := h(ctx, req)
+	return resp
+}
+
+func headersFrom(single map[string]string, multi map[string][]string) http.Header {
+	headers := make(http.Header)
+	if len(multi) > 0 {
+		for name, values := range multi {
+			for _, v := range values {
+				headers.Add(name, v)
+			}
+		}
+		return headers
+	}
+
+	for name, v := range single {
+		headers.Set(name, v)
+	}
+	return headers
+}
+
+// singleHeaders joins repeated headers with commas. Set-Cookie is left out, as cookies
+// cannot be joined that way.
+func singleHeaders(headers http.Header) map[string]string {
+	single := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if http.CanonicalHeaderKey(name) == "Set-Cookie" {
+			continue
+		}
+		single[name] = strings.Join(values, ", ")
+	}
+	return single
+}
+
+func queryFrom(single map[string]string, multi map[string][]string, escaped bool) url.Values {
+	query := make(url.Values)
+	add := func(name, value string) {
+		if escaped {
+			name, value = unescape(name), unescape(value)
+		}
+		query.Add(name, value)
+	}
+
+	if len(multi) > 0 {
+		for name, values := range multi {
+			for _, v := range values {
+				add(name, v)
+			}
+		}
+		return query
+	}
+
+	for name, v := range single {
+		add(name, v)
+	}
+	return query
+}
+
+func unescape(s string) string {
+	unescaped, err := url.QueryUnescape(s)
+	if err != nil {
+		return s
+	}
+	return unescaped
+}
+
+// routeOf takes the path template out of route keys such as "GET /notes/{id}".
+// The $default route has none.
+func routeOf(routeKey string) string {
+	i := strings.Index(routeKey, " ")
+	if i < 0 {
+		return ""
+	}
+	return routeKey[i+1:]
+}
+
+// clientIP takes the client address from X-Forwarded-For, which ALB appends it to
+func clientIP(forwardedFor string) string {
+	addrs := strings.Split(forwardedFor, ",")
+	return strings.TrimSpace(addrs[len(addrs)-1])
+}
+
+// encodeBody base64 encodes bodies that are not valid UTF-8, so binary content survives JSON
+func encodeBody(body string) (string, bool) {
+	if utf8.ValidString(body) {
+		return body, false
+	}
+	return base64.StdEncoding.EncodeToString([]byte(body)), true
+}
+
+func statusCode(resp Response) int {
+	if resp.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,232 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echo answers with a binary body and two cookies, and keeps the request it got
+func echo(got *web.Request) web.Handler {
+	return func(ctx context.Context, req web.Request) (web.Response, error) {
+		*got = req
+		return web.Response{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": {"application/octet-stream"}, "Set-Cookie": {"a=1", "b=2"}},
+			Body:       "\xff\x00",
+		}, nil
+	}
+}
+
+func Test_APIGatewayV1(t *testing.T) {
+	// given
+	var got web.Request
+	var event web.APIGatewayV1Request
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"resource": "/notes/{id}",
+		"path": "/notes/qx2rx",
+		"httpMethod": "GET",
+		"headers": {"password": "mySecretPassword"},
+		"multiValueHeaders": {"password": ["mySecretPassword"], "accept": ["text/plain", "application/json"]},
+		"multiValueQueryStringParameters": {"x": ["1", "2"]},
+		"pathParameters": {"id": "qx2rx"},
+		"requestContext": {"requestId": "req-1", "identity": {"sourceIp": "203.0.113.7"}},
+		"body": "SGVsbG8gV29ybGQ=",
+		"isBase64Encoded": true
+	}`), &event))
+
+	// when
+	resp, err := web.APIGatewayV1(echo(&got))(context.TODO(), event)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, web.Request{
+		Method:         http.MethodGet,
+		Path:           "/notes/qx2rx",
+		Route:          "/notes/{id}",
+		Headers:        http.Header{"Password": {"mySecretPassword"}, "Accept": {"text/plain", "application/json"}},
+		Query:          map[string][]string{"x": {"1", "2"}},
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Body:           "Hello World",
+		RequestID:      "req-1",
+		SourceIP:       "203.0.113.7",
+	}, got)
+	assert.Equal(t, web.APIGatewayV1Response{
+		StatusCode:        http.StatusCreated,
+		MultiValueHeaders: map[string][]string{"Content-Type": {"application/octet-stream"}, "Set-Cookie": {"a=1", "b=2"}},
+		Body:              "/wA=",
+		IsBase64Encoded:   true,
+	}, resp)
+}
+
+func Test_APIGatewayV2(t *testing.T) {
+	// given
+	var got web.Request
+	var event web.APIGatewayV2Request
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"version": "2.0",
+		"routeKey": "GET /notes/{id}",
+		"rawPath": "/notes/qx2rx",
+		"rawQueryString": "x=1&x=2",
+		"cookies": ["c=3", "d=4"],
+		"headers": {"password": "mySecretPassword", "accept": "text/plain,application/json"},
+		"queryStringParameters": {"x": "1,2"},
+		"pathParameters": {"id": "qx2rx"},
+		"requestContext": {"requestId": "req-1", "http": {"method": "GET", "path": "/notes/qx2rx", "sourceIp": "203.0.113.7"}},
+		"body": "Hello World",
+		"isBase64Encoded": false
+	}`), &event))
+
+	// when
+	resp, err := web.APIGatewayV2(echo(&got))(context.TODO(), event)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, web.Request{
+		Method:         http.MethodGet,
+		Path:           "/notes/qx2rx",
+		Route:          "/notes/{id}",
+		Headers:        http.Header{"Password": {"mySecretPassword"}, "Accept": {"text/plain,application/json"}, "Cookie": {"c=3; d=4"}},
+		Query:          map[string][]string{"x": {"1", "2"}},
+		PathParameters: map[string]string{"id": "qx2rx"},
+		Body:           "Hello World",
+		RequestID:      "req-1",
+		SourceIP:       "203.0.113.7",
+	}, got)
+	assert.Equal(t, web.APIGatewayV2Response{
+		StatusCode:      http.StatusCreated,
+		Headers:         map[string]string{"Content-Type": "application/octet-stream"},
+		Cookies:         []string{"a=1", "b=2"},
+		Body:            "/wA=",
+		IsBase64Encoded: true,
+	}, resp)
+}
+
+func Test_ALB(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		wantResp web.ALBResponse
+	}{
+		{
+			name: "single value headers",
+			event: `{
+				"httpMethod": "POST",
+				"path": "/notes",
+				"headers": {"content-type": "application/json", "x-forwarded-for": "198.51.100.1, 203.0.113.7"},
+				"queryStringParameters": {"note%20id": "qx2rx%2F1"},
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/notes/1"}},
+				"body": "Hello World",
+				"isBase64Encoded": false
+			}`,
+			wantResp: web.ALBResponse{
+				StatusCode:        http.StatusCreated,
+				StatusDescription: "201 Created",
+				Headers:           map[string]string{"Content-Type": "application/octet-stream", "Set-Cookie": "a=1"},
+				Body:              "/wA=",
+				IsBase64Encoded:   true,
+			},
+		},
+		{
+			name: "multi value headers",
+			event: `{
+				"httpMethod": "POST",
+				"path": "/notes",
+				"multiValueHeaders": {"content-type": ["application/json"], "x-forwarded-for": ["198.51.100.1, 203.0.113.7"]},
+				"multiValueQueryStringParameters": {"note%20id": ["qx2rx%2F1"]},
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/notes/1"}},
+				"body": "SGVsbG8gV29ybGQ=",
+				"isBase64Encoded": true
+			}`,
+			wantResp: web.ALBResponse{
+				StatusCode:        http.StatusCreated,
+				StatusDescription: "201 Created",
+				MultiValueHeaders: map[string][]string{"Content-Type": {"application/octet-stream"}, "Set-Cookie": {"a=1", "b=2"}},
+				Body:              "/wA=",
+				IsBase64Encoded:   true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var got web.Request
+			var event web.ALBRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.event), &event))
+
+			// when
+			resp, err := web.ALB(echo(&got))(context.TODO(), event)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, http.MethodPost, got.Method)
+			assert.Equal(t, "/notes", got.Path)
+			assert.Equal(t, "application/json", got.Headers.Get("Content-Type"))
+			assert.Equal(t, "qx2rx/1", got.Query.Get("note id"))
+			assert.Equal(t, "Hello World", got.Body)
+			assert.Equal(t, "203.0.113.7", got.SourceIP)
+			assert.Equal(t, tt.wantResp, resp)
+		})
+	}
+}
+
+func Test_LambdaHandlerDetectsEventFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		wantResp interface{}
+	}{
+		{
+			name:     "rest api",
+			event:    `{"httpMethod": "GET", "path": "/notes", "requestContext": {"requestId": "req-1"}}`,
+			wantResp: web.APIGatewayV1Response{},
+		},
+		{
+			name:     "http api",
+			event:    `{"version": "2.0", "rawPath": "/notes", "requestContext": {"http": {"method": "GET"}}}`,
+			wantResp: web.APIGatewayV2Response{},
+		},
+		{
+			name:     "alb",
+			event:    `{"httpMethod": "GET", "path": "/notes", "requestContext": {"elb": {"targetGroupArn": "arn"}}}`,
+			wantResp: web.ALBResponse{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var got web.Request
+			handler := web.LambdaHandler(echo(&got))
+
+			// when
+			resp, err := handler(context.TODO(), json.RawMessage(tt.event))
+
+			// then
+			assert.NoError(t, err)
+			assert.IsType(t, tt.wantResp, resp)
+			assert.Equal(t, http.MethodGet, got.Method)
+			assert.Equal(t, "/notes", got.Path)
+		})
+	}
+}
+
+func Test_LambdaHandlerRejectsMalformedBase64Body(t *testing.T) {
+	// given
+	var got web.Request
+	event := web.APIGatewayV1Request{HTTPMethod: http.MethodPost, Path: "/notes", Body: "not base64!", IsBase64Encoded: true}
+
+	// when
+	resp, err := web.APIGatewayV1(echo(&got))(context.TODO(), event)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Empty(t, got.Method, "handler must not be called")
+}
@@ -2,40 +2,85 @@ package web
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/projects/secure-notes/internal/platform/logging"
 	"go.uber.org/zap"
 )
 
+// RequestIDHeader echoes the ID of each request, so clients can quote it to support
+const RequestIDHeader = "X-Request-ID"
+
 type Handler func(ctx context.Context, req Request) (Response, error)
 
 type Middleware struct {
 	Logger *zap.SugaredLogger
+
+	// IPHashKey keys the hash of logged source IPs, so they cannot be recovered
+	// by hashing the whole address space.
+	IPHashKey []byte
+
+	CORS CORS
 }
 
 func (m *Middleware) WrapWithCorsAndLogging(h Handler) func(ctx context.Context, req Request) (Response, error) {
-	return func(ctx context.Context, req Request) (Response, error) {
+	h = m.CORS.Handler(m.Recover(h))
+	return m.WithRequestID(func(ctx context.Context, req Request) (Response, error) {
+		start := time.Now()
 		resp, err := h(ctx, req)
-		resp = addCorsHeaders(resp)
 
 		if err != nil {
-			m.Logger.Errorw("failed to handle request successfully",
-				"error", err,
-				"request", req,
-				"response", resp)
+			fields := append(m.requestFields(req, resp, time.Since(start)), "error", err)
+			m.Logger.Errorw("failed to handle request successfully", fields...)
 		}
 
 		return resp, nil
+	})
+}
+
+// WithRequestID identifies each request by the ID assigned by API Gateway or, outside of it,
+// by a new UUID. The ID is stored in the request and its context together with a logger
+// tagged with it, and echoed in the X-Request-ID response header.
+func (m *Middleware) WithRequestID(h Handler) Handler {
+	return func(ctx context.Context, req Request) (Response, error) {
+		if req.RequestID == "" {
+			req.RequestID = uuid.New().String()
+		}
+		ctx = logging.WithRequestID(ctx, m.Logger, req.RequestID)
+
+		resp, err := h(ctx, req)
+		if resp.Headers == nil {
+			resp.Headers = make(http.Header)
+		}
+		resp.Headers.Set(RequestIDHeader, req.RequestID)
+
+		return resp, err
+	}
+}
+
+// requestFields returns the allowlist of request properties that may be logged.
+// Headers and bodies are never logged, because they carry passwords and note text.
+func (m *Middleware) requestFields(req Request, resp Response, latency time.Duration) []interface{} {
+	return []interface{}{
+		"method", req.Method,
+		"path", req.Path,
+		"requestId", req.RequestID,
+		"sourceIpHash", m.hashIP(req.SourceIP),
+		"status", resp.StatusCode,
+		"latency", latency,
 	}
 }
 
-func addCorsHeaders(original Response) Response {
-	return Response{
-		StatusCode: original.StatusCode,
-		Headers: map[string]string{
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		Body:            original.Body,
-		IsBase64Encoded: original.IsBase64Encoded,
+func (m *Middleware) hashIP(ip string) string {
+	if ip == "" {
+		return ""
 	}
+	mac := hmac.New(sha256.New, m.IPHashKey)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
 }
@@ -0,0 +1,207 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/logging"
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	secretPassword = "mySecretPassword"
+	secretText     = "launch codes 0000"
+)
+
+func Test_WrapWithCorsAndLoggingNeverLogsSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		req  web.Request
+	}{
+		{
+			name: "get note with password header",
+			req: web.Request{
+				Method:         http.MethodGet,
+				Path:           "/notes/qx2rx",
+				PathParameters: map[string]string{"id": "qx2rx"},
+				Headers:        http.Header{"Password": {secretPassword}},
+			},
+		},
+		{
+			name: "create note with secret body",
+			req: web.Request{
+				Method: http.MethodPost,
+				Path:   "/notes",
+				Body:   fmt.Sprintf(`{"text":%q,"password":%q,"lifeTimeSeconds":60}`, secretText, secretPassword),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			core, logs := observer.New(zapcore.DebugLevel)
+			m := web.Middleware{Logger: zap.New(core).Sugar(), IPHashKey: []byte("key")}
+
+			failing := func(ctx context.Context, req web.Request) (web.Response, error) {
+				return web.Response{StatusCode: http.StatusUnauthorized}, errors.New("wrong password")
+			}
+
+			// when
+			_, _ = m.WrapWithCorsAndLogging(failing)(context.TODO(), tt.req)
+
+			// then
+			assert.Equal(t, 1, logs.Len())
+			for _, entry := range logs.All() {
+				logged := fmt.Sprintf("%s %v", entry.Message, entry.ContextMap())
+				assert.False(t, strings.Contains(logged, secretPassword), "password logged: %s", logged)
+				assert.False(t, strings.Contains(logged, secretText), "note text logged: %s", logged)
+			}
+		})
+	}
+}
+
+func Test_WrapWithCorsAndLoggingLogsAllowlistedFields(t *testing.T) {
+	// given
+	core, logs := observer.New(zapcore.DebugLevel)
+	m := web.Middleware{Logger: zap.New(core).Sugar(), IPHashKey: []byte("key")}
+
+	req := web.Request{
+		Method:  http.MethodGet,
+		Path:    "/notes/qx2rx",
+		Headers: http.Header{"Password": {secretPassword}},
+	}
+	req.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+	req.SourceIP = "203.0.113.7"
+
+	failing := func(ctx context.Context, req web.Request) (web.Response, error) {
+		return web.Response{StatusCode: http.StatusNotFound}, errors.New("note not found")
+	}
+
+	// when
+	_, _ = m.WrapWithCorsAndLogging(failing)(context.TODO(), req)
+
+	// then
+	fields := logs.All()[0].ContextMap()
+	assert.ElementsMatch(t,
+		[]string{"method", "path", "requestId", "sourceIpHash", "status", "latency", "error"},
+		keys(fields))
+	assert.Equal(t, http.MethodGet, fields["method"])
+	assert.Equal(t, "/notes/qx2rx", fields["path"])
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", fields["requestId"])
+	assert.Equal(t, int64(http.StatusNotFound), fields["status"])
+	assert.Len(t, fields["sourceIpHash"], 16)
+	assert.NotContains(t, fields["sourceIpHash"], "203.0.113.7")
+}
+
+func keys(m map[string]interface{}) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func Test_WrapWithCorsAndLoggingKeepsHandlerHeaders(t *testing.T) {
+	// given
+	m := web.Middleware{Logger: zap.NewNop().Sugar(), CORS: web.CORS{AllowedOrigins: []string{"*"}}}
+
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		return web.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Headers:    http.Header{"Retry-After": {"2"}},
+		}, nil
+	}
+
+	// when
+	gotResp, _ := m.WrapWithCorsAndLogging(handler)(context.TODO(), web.Request{})
+
+	// then
+	assert.Equal(t, "2", gotResp.Headers.Get("Retry-After"))
+	assert.Equal(t, "*", gotResp.Headers.Get("Access-Control-Allow-Origin"))
+}
+
+func Test_WithRequestIDKeepsAPIGatewayID(t *testing.T) {
+	// given
+	core, logs := observer.New(zapcore.DebugLevel)
+	m := web.Middleware{Logger: zap.New(core).Sugar()}
+
+	var gotReqID, gotCtxID string
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		gotReqID = req.RequestID
+		gotCtxID = logging.RequestID(ctx)
+		logging.FromContext(ctx).Info("handling")
+		return web.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	req := web.Request{}
+	req.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+
+	// when
+	gotResp, _ := m.WithRequestID(handler)(context.TODO(), req)
+
+	// then
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", gotReqID)
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", gotCtxID)
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", gotResp.Headers.Get(web.RequestIDHeader))
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", logs.All()[0].ContextMap()["requestId"])
+}
+
+func Test_WithRequestIDGeneratesMissingID(t *testing.T) {
+	// given
+	m := web.Middleware{Logger: zap.NewNop().Sugar()}
+
+	var gotCtxID string
+	handler := func(ctx context.Context, req web.Request) (web.Response, error) {
+		gotCtxID = logging.RequestID(ctx)
+		return web.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	// when
+	gotResp, _ := m.WrapWithCorsAndLogging(handler)(context.TODO(), web.Request{})
+	gotOtherResp, _ := m.WrapWithCorsAndLogging(handler)(context.TODO(), web.Request{})
+
+	// then
+	assert.Len(t, gotCtxID, 36)
+	assert.Equal(t, gotCtxID, gotOtherResp.Headers.Get(web.RequestIDHeader))
+	assert.NotEqual(t, gotResp.Headers.Get(web.RequestIDHeader), gotOtherResp.Headers.Get(web.RequestIDHeader))
+}
+
+func Test_WrapWithCorsAndLoggingRecoversFromPanic(t *testing.T) {
+	// given
+	core, logs := observer.New(zapcore.DebugLevel)
+	m := web.Middleware{Logger: zap.New(core).Sugar(), CORS: web.CORS{AllowedOrigins: []string{"*"}}}
+
+	panicking := func(ctx context.Context, req web.Request) (web.Response, error) {
+		var notes map[string]string
+		notes["qx2rx"] = secretText
+		return web.Response{}, nil
+	}
+
+	req := web.Request{}
+	req.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+
+	// when
+	gotResp, gotErr := m.WrapWithCorsAndLogging(panicking)(context.TODO(), req)
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, http.StatusInternalServerError, gotResp.StatusCode)
+	assert.Equal(t, "application/problem+json", gotResp.Headers.Get("Content-Type"))
+	assert.Equal(t, "*", gotResp.Headers.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", gotResp.Headers.Get(web.RequestIDHeader))
+
+	panics := logs.FilterMessage("recovered from panic").All()
+	if assert.Len(t, panics, 1) {
+		assert.Contains(t, panics[0].ContextMap()["stack"], "runtime/debug.Stack")
+		assert.Equal(t, "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", panics[0].ContextMap()["requestId"])
+	}
+}
@@ -0,0 +1,25 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/projects/secure-notes/internal/platform/logging"
+)
+
+// Recover turns a panic of h into a 500 problem response, so callers still get a proper
+// response with CORS headers instead of a failed invocation. The stack trace is logged.
+func (m *Middleware) Recover(h Handler) Handler {
+	return func(ctx context.Context, req Request) (resp Response, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.FromContext(ctx).Errorw("recovered from panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+				resp = InternalServerError(req)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		return h(ctx, req)
+	}
+}
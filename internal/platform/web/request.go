@@ -0,0 +1,31 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Request is an HTTP request independent of the event format or server that delivered it.
+// Adapters convert API Gateway, ALB and net/http requests into it.
+type Request struct {
+	Method string
+	Path   string
+	// Route is the path template resolved by the gateway, such as /notes/{id}, or empty
+	Route string
+	// Headers are looked up case-insensitively and carry cookies in the Cookie header
+	Headers        http.Header
+	Query          url.Values
+	PathParameters map[string]string
+	// Body is decoded already, so it is never base64 encoded
+	Body      string
+	RequestID string
+	SourceIP  string
+}
+
+// Response is an HTTP response independent of the event format or server returning it.
+// Set-Cookie headers are passed as cookies to event formats that have them.
+type Response struct {
+	StatusCode int
+	Headers    http.Header
+	Body       string
+}
@@ -1,12 +1,94 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
 )
 
-func InternalServerError() Response {
+// Content types of response bodies
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeProblem = "application/problem+json"
+)
+
+// Error codes of problem responses. They are part of the API, so clients can rely on them
+// instead of parsing titles or details.
+const (
+	CodeMalformedRequest = "malformed_request"
+	CodeRequestTooLarge  = "request_too_large"
+	CodeValidationFailed = "validation_failed"
+	CodeNoteNotFound     = "note_not_found"
+	CodeRouteNotFound    = "route_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInvalidPassword  = "invalid_password"
+	CodeNotAuthorized    = "not_authorized"
+	CodeRateLimited      = "rate_limited"
+	CodeInternalError    = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details body extended with a stable error code
+// and the ID of the request, so users can quote it when contacting support.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Code          string         `json:"code"`
+	RequestID     string         `json:"requestId,omitempty"`
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+}
+
+// InvalidParam tells which request field is invalid and why
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// JSON responds with v encoded as JSON
+func JSON(status int, v interface{}) (Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{
+		StatusCode: status,
+		Headers:    http.Header{"Content-Type": {ContentTypeJSON}},
+		Body:       string(body),
+	}, nil
+}
+
+// ProblemResponse responds with an application/problem+json body for status and code.
+// Empty type and title default to "about:blank" and the status text.
+func ProblemResponse(req Request, status int, code string, p Problem) Response {
+	p.Status = status
+	p.Code = code
+	p.RequestID = req.RequestID
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(status)
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return Response{StatusCode: http.StatusInternalServerError}
+	}
+
 	return Response{
-		StatusCode: http.StatusInternalServerError,
-		Body:       "Internal server error",
+		StatusCode: status,
+		Headers:    http.Header{"Content-Type": {ContentTypeProblem}},
+		Body:       string(body),
 	}
 }
+
+// Error responds with a problem that has no details beyond its status and code
+func Error(req Request, status int, code string) Response {
+	return ProblemResponse(req, status, code, Problem{})
+}
+
+// InternalServerError never describes the cause, as it may reveal internals
+func InternalServerError(req Request) Response {
+	return Error(req, http.StatusInternalServerError, CodeInternalError)
+}
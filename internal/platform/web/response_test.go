@@ -0,0 +1,53 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/projects/secure-notes/internal/platform/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProblemResponse(t *testing.T) {
+	// given
+	req := web.Request{}
+	req.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+
+	// when
+	gotResp := web.ProblemResponse(req, http.StatusUnprocessableEntity, web.CodeValidationFailed, web.Problem{
+		Title:         "Note is invalid",
+		InvalidParams: []web.InvalidParam{{Name: "text", Reason: "text is required"}},
+	})
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Note is invalid","status":422,"code":"validation_failed","requestId":"c6af9ac6-7b61-11e6-9a41-93e8deadbeef","invalidParams":[{"name":"text","reason":"text is required"}]}`,
+	}, gotResp)
+}
+
+func Test_ErrorDefaultsTitleToStatusText(t *testing.T) {
+	// when
+	gotResp := web.Error(web.Request{}, http.StatusNotFound, web.CodeNoteNotFound)
+
+	// then
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusNotFound,
+		Headers:    http.Header{"Content-Type": {"application/problem+json"}},
+		Body:       `{"type":"about:blank","title":"Not Found","status":404,"code":"note_not_found"}`,
+	}, gotResp)
+}
+
+func Test_JSON(t *testing.T) {
+	// when
+	gotResp, gotErr := web.JSON(http.StatusCreated, map[string]string{"id": "qx2rx"})
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, web.Response{
+		StatusCode: http.StatusCreated,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"id":"qx2rx"}`,
+	}, gotResp)
+}
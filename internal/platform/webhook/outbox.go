@@ -0,0 +1,206 @@
+// Package webhook delivers signed JSON events to URLs chosen by note creators.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the request body
+const SignatureHeader = "X-Signature-256"
+
+var (
+	// ErrQueueFull is used when deliveries are produced faster than they can be sent.
+	ErrQueueFull = errors.New("webhook queue full")
+
+	// ErrClosed is used when an event is enqueued after the outbox was closed.
+	ErrClosed = errors.New("webhook outbox closed")
+)
+
+// Config tunes delivery of an Outbox. Zero values fall back to defaults.
+type Config struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for every further retry
+	Backoff time.Duration
+	// OnError is called with deliveries that failed for good
+	OnError func(error)
+}
+
+const (
+	defaultWorkers     = 4
+	defaultQueueSize   = 1000
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+)
+
+type delivery struct {
+	url    string
+	secret string
+	body   []byte
+}
+
+// Outbox queues events in memory and delivers them from background workers with retries,
+// so producers never wait for the receiver. Queued events are lost when the process exits
+// before Close returns, so it suits long running servers only. On Lambda, where the process
+// is frozen between invocations, use Queue.
+type Outbox struct {
+	client *http.Client
+	config Config
+
+	queue chan delivery
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewOutbox starts delivery workers, which run until Close is called
+func NewOutbox(client *http.Client, c Config) *Outbox {
+	if c.Workers <= 0 {
+		c.Workers = defaultWorkers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defaultBackoff
+	}
+	if c.OnError == nil {
+		c.OnError = func(error) {}
+	}
+
+	o := &Outbox{
+		client: client,
+		config: c,
+		queue:  make(chan delivery, c.QueueSize),
+	}
+	for i := 0; i < c.Workers; i++ {
+		o.wg.Add(1)
+		go o.work()
+	}
+	return o
+}
+
+// Enqueue signs event with secret and schedules its delivery to url without waiting for it
+func (o *Outbox) Enqueue(url, secret string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal webhook event: %w", err)
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.closed {
+		return ErrClosed
+	}
+
+	select {
+	case o.queue <- delivery{url: url, secret: secret, body: body}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting events and waits until queued ones are delivered or ctx is done
+func (o *Outbox) Close(ctx context.Context) error {
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.queue)
+	}
+	o.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (o *Outbox) work() {
+	defer o.wg.Done()
+	for d := range o.queue {
+		if err := o.deliver(d); err != nil {
+			o.config.OnError(err)
+		}
+	}
+}
+
+// deliver retries network errors, 429 and 5xx responses with exponential backoff.
+// Other responses mean the receiver rejected the event, so retrying would not help.
+func (o *Outbox) deliver(d delivery) error {
+	wait := o.config.Backoff
+	var err error
+	for attempt := 1; attempt <= o.config.MaxAttempts; attempt++ {
+		var retry bool
+		retry, err = send(o.client, d)
+		if err == nil || !retry {
+			break
+		}
+		if attempt < o.config.MaxAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("deliver webhook to %s: %w", d.url, err)
+	}
+	return nil
+}
+
+// send makes one delivery attempt and tells whether a failure is worth retrying
+func send(client *http.Client, d delivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(d.secret, d.body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("receiver rejected event with %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the value of SignatureHeader for body, so receivers can verify events
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
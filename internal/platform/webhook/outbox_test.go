@@ -0,0 +1,168 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/platform/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	NoteID string `json:"noteId"`
+}
+
+func Test_OutboxDeliversSignedEvent(t *testing.T) {
+	// given
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	outbox := webhook.NewOutbox(server.Client(), webhook.Config{})
+
+	// when
+	err := outbox.Enqueue(server.URL, "secret", event{NoteID: "qx2rx"})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Close(context.TODO()))
+
+	// then
+	req := <-received
+	body := <-bodies
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"noteId":"qx2rx"}`, string(body))
+	assert.Equal(t, webhook.Sign("secret", body), req.Header.Get(webhook.SignatureHeader))
+}
+
+func Test_OutboxRetriesFailedDelivery(t *testing.T) {
+	// given
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var errs []error
+	outbox := webhook.NewOutbox(server.Client(), webhook.Config{
+		Backoff: time.Millisecond,
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+
+	// when
+	require.NoError(t, outbox.Enqueue(server.URL, "secret", event{NoteID: "qx2rx"}))
+	require.NoError(t, outbox.Close(context.TODO()))
+
+	// then
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Empty(t, errs)
+}
+
+func Test_OutboxGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{name: "after max attempts", status: http.StatusInternalServerError, wantCalls: 3},
+		{name: "when receiver rejects event", status: http.StatusBadRequest, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			var (
+				mu   sync.Mutex
+				errs []error
+			)
+			outbox := webhook.NewOutbox(server.Client(), webhook.Config{
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				OnError: func(err error) {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				},
+			})
+
+			// when
+			require.NoError(t, outbox.Enqueue(server.URL, "secret", event{NoteID: "qx2rx"}))
+			require.NoError(t, outbox.Close(context.TODO()))
+
+			// then
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+			assert.Len(t, errs, 1)
+		})
+	}
+}
+
+func Test_OutboxEnqueueDoesNotWaitForDelivery(t *testing.T) {
+	// given
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	outbox := webhook.NewOutbox(server.Client(), webhook.Config{})
+
+	// when
+	start := time.Now()
+	err := outbox.Enqueue(server.URL, "secret", event{NoteID: "qx2rx"})
+	elapsed := time.Since(start)
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, elapsed < 100*time.Millisecond, "enqueue took %s", elapsed)
+	close(release)
+	assert.NoError(t, outbox.Close(context.TODO()))
+}
+
+func Test_OutboxEnqueueAfterClose(t *testing.T) {
+	// given
+	outbox := webhook.NewOutbox(http.DefaultClient, webhook.Config{})
+	require.NoError(t, outbox.Close(context.TODO()))
+
+	// when
+	err := outbox.Enqueue("https://example.com", "secret", event{NoteID: "qx2rx"})
+
+	// then
+	assert.True(t, errors.Is(err, webhook.ErrClosed))
+}
+
+func Test_ClientRefusesPrivateAddresses(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach a loopback address")
+	}))
+	defer server.Close()
+
+	client := webhook.NewClient(time.Second)
+
+	// when
+	_, err := client.Post(server.URL, "application/json", nil)
+
+	// then
+	assert.True(t, errors.Is(err, webhook.ErrForbiddenAddress), "got %v", err)
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// message is a delivery as stored in SQS
+type message struct {
+	URL    string          `json:"url"`
+	Secret string          `json:"secret"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// Queue is an outbox backed by an SQS queue. Unlike Outbox it keeps events when the process
+// is frozen or recycled, as Lambda does once a response is returned, provided Flush is called
+// before that. A function subscribed to the queue delivers them with Receive.
+type Queue struct {
+	client   *sqs.Client
+	queueURL string
+	timeout  time.Duration
+	onError  func(error)
+
+	wg sync.WaitGroup
+}
+
+// NewQueue provides an outbox sending events to the SQS queue at queueURL. Messages that could
+// not be sent within timeout are passed to onError and dropped.
+func NewQueue(client *sqs.Client, queueURL string, timeout time.Duration, onError func(error)) *Queue {
+	if onError == nil {
+		onError = func(error) {}
+	}
+	return &Queue{client: client, queueURL: queueURL, timeout: timeout, onError: onError}
+}
+
+// Enqueue sends event with url and secret to the queue in the background, so the caller never
+// waits for SQS. Only events that cannot be marshalled fail at once.
+func (q *Queue) Enqueue(url, secret string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal webhook event: %w", err)
+	}
+	msg, err := json.Marshal(message{URL: url, Secret: secret, Event: body})
+	if err != nil {
+		return fmt.Errorf("marshal webhook message: %w", err)
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		if err := q.send(msg); err != nil {
+			q.onError(fmt.Errorf("sqs send webhook message to %s: %w", url, err))
+		}
+	}()
+	return nil
+}
+
+func (q *Queue) send(msg []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	input := sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.queueURL),
+		MessageBody: aws.String(string(msg)),
+	}
+	_, err := q.client.SendMessageRequest(&input).Send(ctx)
+	return err
+}
+
+// Flush waits until messages being sent are stored in SQS, dropped, or ctx is done. Each send
+// is bounded by the queue timeout, so Flush does not wait longer than that.
+func (q *Queue) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close waits for messages being sent like Flush
+func (q *Queue) Close(ctx context.Context) error {
+	return q.Flush(ctx)
+}
+
+// Receive returns a Lambda handler delivering events queued by Queue, one attempt per message.
+// Retryable failures fail the invocation, so SQS delivers the message again after its visibility
+// timeout until it is moved to the dead-letter queue. Events the receiver rejected are passed
+// to onError and dropped. The handler expects batches of one message, otherwise messages
+// delivered already are sent again with a failed one.
+func Receive(client *http.Client, onError func(error)) func(ctx context.Context, e events.SQSEvent) error {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	return func(ctx context.Context, e events.SQSEvent) error {
+		for _, record := range e.Records {
+			var m message
+			if err := json.Unmarshal([]byte(record.Body), &m); err != nil {
+				onError(fmt.Errorf("unmarshal webhook message %s: %w", record.MessageId, err))
+				continue
+			}
+
+			retry, err := send(client, delivery{url: m.URL, secret: m.Secret, body: m.Event})
+			if err == nil {
+				continue
+			}
+			err = fmt.Errorf("deliver webhook to %s: %w", m.URL, err)
+			if retry {
+				return err
+			}
+			onError(err)
+		}
+		return nil
+	}
+}
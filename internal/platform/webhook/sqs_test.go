@@ -0,0 +1,165 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/defaults"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/projects/secure-notes/internal/platform/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSQS answers SendMessage and passes message bodies to sent
+func fakeSQS(t *testing.T, sent chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "SendMessage", r.Form.Get("Action"))
+		body := r.Form.Get("MessageBody")
+		sent <- body
+
+		sum := md5.Sum([]byte(body))
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<SendMessageResponse><SendMessageResult><MessageId>m-1</MessageId>`+
+			`<MD5OfMessageBody>%s</MD5OfMessageBody></SendMessageResult></SendMessageResponse>`, hex.EncodeToString(sum[:]))
+	}))
+}
+
+func sqsClient(url string) *sqs.Client {
+	cfg := defaults.Config()
+	cfg.Region = "us-east-1"
+	cfg.Credentials = aws.NewStaticCredentialsProvider("AKID", "SECRET", "")
+	cfg.EndpointResolver = aws.ResolveWithEndpointURL(url)
+	return sqs.New(cfg)
+}
+
+func Test_QueueDeliversThroughSQS(t *testing.T) {
+	// given
+	sent := make(chan string, 1)
+	queue := fakeSQS(t, sent)
+	defer queue.Close()
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	outbox := webhook.NewQueue(sqsClient(queue.URL), queue.URL+"/123456789012/webhooks", time.Second, nil)
+
+	// when
+	enqueueErr := outbox.Enqueue(receiver.URL, "secret", event{NoteID: "qx2rx"})
+	require.NoError(t, enqueueErr)
+	receiveErr := webhook.Receive(receiver.Client(), nil)(context.TODO(), events.SQSEvent{
+		Records: []events.SQSMessage{{MessageId: "m-1", Body: <-sent}},
+	})
+
+	// then
+	assert.NoError(t, receiveErr)
+	req := <-received
+	body := <-bodies
+	assert.JSONEq(t, `{"noteId":"qx2rx"}`, string(body))
+	assert.Equal(t, webhook.Sign("secret", body), req.Header.Get(webhook.SignatureHeader))
+}
+
+func Test_ReceiveFailsOnlyRetryableDeliveries(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantErr     bool
+		wantDropped bool
+	}{
+		{name: "delivered", status: http.StatusNoContent},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+		{name: "rejected", status: http.StatusGone, wantDropped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			var dropped []error
+			handler := webhook.Receive(receiver.Client(), func(err error) { dropped = append(dropped, err) })
+			record := events.SQSMessage{
+				MessageId: "m-1",
+				Body:      fmt.Sprintf(`{"url":%q,"secret":"secret","event":{"noteId":"qx2rx"}}`, receiver.URL),
+			}
+
+			// when
+			err := handler(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{record}})
+
+			// then
+			assert.Equal(t, tt.wantErr, err != nil, "got %v", err)
+			assert.Equal(t, tt.wantDropped, len(dropped) == 1, "got %v", dropped)
+		})
+	}
+}
+
+func Test_QueueEnqueueFailure(t *testing.T) {
+	// given
+	queue := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer queue.Close()
+	client := sqsClient(queue.URL)
+	client.Retryer = aws.NoOpRetryer{}
+
+	dropped := make(chan error, 1)
+	outbox := webhook.NewQueue(client, queue.URL+"/123456789012/webhooks", time.Second, func(err error) {
+		dropped <- err
+	})
+
+	// when
+	enqueueErr := outbox.Enqueue("https://hooks.example.com/read", "secret", event{NoteID: "qx2rx"})
+	flushErr := outbox.Flush(context.TODO())
+
+	// then
+	assert.NoError(t, enqueueErr, "sending fails in the background")
+	assert.NoError(t, flushErr)
+	assert.Error(t, <-dropped)
+}
+
+func Test_QueueEnqueueDoesNotWaitForSQS(t *testing.T) {
+	// given
+	release := make(chan struct{})
+	sent := make(chan string, 1)
+	slow := fakeSQS(t, sent)
+	queue := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		slow.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+	defer queue.Close()
+
+	outbox := webhook.NewQueue(sqsClient(queue.URL), queue.URL+"/123456789012/webhooks", time.Second, nil)
+
+	// when
+	start := time.Now()
+	enqueueErr := outbox.Enqueue("https://hooks.example.com/read", "secret", event{NoteID: "qx2rx"})
+	elapsed := time.Since(start)
+	close(release)
+	flushErr := outbox.Flush(context.TODO())
+
+	// then
+	assert.NoError(t, enqueueErr)
+	assert.Less(t, int64(elapsed), int64(500*time.Millisecond))
+	assert.NoError(t, flushErr)
+	assert.JSONEq(t, `{"url":"https://hooks.example.com/read","secret":"secret","event":{"noteId":"qx2rx"}}`, <-sent)
+}
@@ -0,0 +1,159 @@
+package bolt
+
+import (
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/security"
+)
+
+// Note defines properties of a secured note that is persisted in the database file
+type Note struct {
+	ID         string            `json:"pk"`
+	Sealed     *sealedText       `json:"sealed,omitempty"`
+	TTL        int64             `json:"ttl"`
+	ReadsLeft  int               `json:"readsLeft,omitempty"`
+	Opaque     bool              `json:"opaque,omitempty"`
+	Ciphertext string            `json:"ciphertext,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+
+	MaxFailedAttempts int   `json:"maxFailedAttempts,omitempty"`
+	FailedAttempts    int   `json:"failedAttempts,omitempty"`
+	LastFailedAt      int64 `json:"lastFailedAt,omitempty"`
+
+	ManagementTokenHash string `json:"managementTokenHash,omitempty"`
+	WebhookURL          string `json:"webhookUrl,omitempty"`
+	WebhookSecret       string `json:"webhookSecret,omitempty"`
+	ReadCount           int    `json:"readCount,omitempty"`
+	LastReadAt          int64  `json:"lastReadAt,omitempty"`
+	// Consumed notes had their last read taken and only keep their status until they expire
+	Consumed bool `json:"consumed,omitempty"`
+}
+
+// sealedText defines encrypted note text together with identifiers and parameters needed to open it
+type sealedText struct {
+	Cipher      string `json:"cipher"`
+	KDF         string `json:"kdf"`
+	KDFTime     uint32 `json:"kdfTime"`
+	KDFMemory   uint32 `json:"kdfMemory"`
+	KDFThreads  uint8  `json:"kdfThreads"`
+	PepperKeyID string `json:"pepperKeyId,omitempty"`
+	Salt        []byte `json:"salt"`
+	Nonce       []byte `json:"nonce"`
+	Ciphertext  []byte `json:"ciphertext"`
+}
+
+func toNote(sn creating.SecureNote) Note {
+	n := Note{
+		ID:         sn.ID,
+		TTL:        sn.TTL,
+		ReadsLeft:  sn.MaxReads,
+		Opaque:     sn.Opaque,
+		Ciphertext: sn.Ciphertext,
+		Metadata:   sn.Metadata,
+
+		MaxFailedAttempts: sn.MaxFailedAttempts,
+
+		ManagementTokenHash: sn.ManagementTokenHash,
+		WebhookURL:          sn.WebhookURL,
+		WebhookSecret:       sn.WebhookSecret,
+	}
+	if !sn.Opaque {
+		n.Sealed = &sealedText{
+			Cipher:      sn.Sealed.Cipher,
+			KDF:         sn.Sealed.KDF,
+			KDFTime:     sn.Sealed.KDFParams.Time,
+			KDFMemory:   sn.Sealed.KDFParams.Memory,
+			KDFThreads:  sn.Sealed.KDFParams.Threads,
+			PepperKeyID: sn.Sealed.PepperKeyID,
+			Salt:        sn.Sealed.Salt,
+			Nonce:       sn.Sealed.Nonce,
+			Ciphertext:  sn.Sealed.Ciphertext,
+		}
+	}
+	return n
+}
+
+func (n Note) toGetting() getting.SecureNote {
+	note := getting.SecureNote{
+		ID:         n.ID,
+		TTL:        n.TTL,
+		ReadsLeft:  n.ReadsLeft,
+		Opaque:     n.Opaque,
+		Ciphertext: n.Ciphertext,
+		Metadata:   n.Metadata,
+
+		MaxFailedAttempts: n.MaxFailedAttempts,
+		FailedAttempts:    n.FailedAttempts,
+		LastFailedAt:      n.LastFailedAt,
+
+		WebhookURL:    n.WebhookURL,
+		WebhookSecret: n.WebhookSecret,
+	}
+	if n.Sealed != nil {
+		note.Sealed = security.SealedText{
+			Cipher: n.Sealed.Cipher,
+			KDF:    n.Sealed.KDF,
+			KDFParams: security.KDFParams{
+				Time:    n.Sealed.KDFTime,
+				Memory:  n.Sealed.KDFMemory,
+				Threads: n.Sealed.KDFThreads,
+			},
+			PepperKeyID: n.Sealed.PepperKeyID,
+			Salt:        n.Sealed.Salt,
+			Nonce:       n.Sealed.Nonce,
+			Ciphertext:  n.Sealed.Ciphertext,
+		}
+	}
+	return note
+}
+
+// noteMeta is stored under the note ID in its own bucket, so describing a note never decodes its content
+type noteMeta struct {
+	ID        string `json:"pk"`
+	TTL       int64  `json:"ttl"`
+	ReadsLeft int    `json:"readsLeft,omitempty"`
+	Opaque    bool   `json:"opaque,omitempty"`
+	Consumed  bool   `json:"consumed,omitempty"`
+}
+
+func (n Note) meta() noteMeta {
+	return noteMeta{
+		ID:        n.ID,
+		TTL:       n.TTL,
+		ReadsLeft: n.ReadsLeft,
+		Opaque:    n.Opaque,
+		Consumed:  n.Consumed,
+	}
+}
+
+func (m noteMeta) toGetting() getting.SecureNoteMeta {
+	return getting.SecureNoteMeta{
+		ID:        m.ID,
+		TTL:       m.TTL,
+		ReadsLeft: m.ReadsLeft,
+		Opaque:    m.Opaque,
+	}
+}
+
+func (n Note) toManagingStatus() managing.SecureNoteStatus {
+	return managing.SecureNoteStatus{
+		ID:                  n.ID,
+		ManagementTokenHash: n.ManagementTokenHash,
+		TTL:                 n.TTL,
+		ReadsLeft:           n.ReadsLeft,
+		ReadCount:           n.ReadCount,
+		LastReadAt:          n.LastReadAt,
+		Consumed:            n.Consumed,
+	}
+}
+
+// consume drops note content, so only its status remains
+func (n *Note) consume() {
+	n.Sealed = nil
+	n.Ciphertext = ""
+	n.Metadata = nil
+	n.WebhookURL = ""
+	n.WebhookSecret = ""
+	n.Consumed = true
+}
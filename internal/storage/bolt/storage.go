@@ -0,0 +1,358 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	notesBucket = []byte("notes")
+	metaBucket  = []byte("meta")
+)
+
+// Storage keeps notes in an embedded bbolt database file. It is meant for self-hosted deployments.
+type Storage struct {
+	db  *bbolt.DB
+	now func() time.Time
+}
+
+// Open opens or creates the database file at path
+func Open(path string, now func() time.Time) (*Storage, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(notesBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(metaBucket); err != nil {
+			return err
+		}
+		return addMissingMeta(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create notes buckets: %w", err)
+	}
+
+	return &Storage{db: db, now: now}, nil
+}
+
+// addMissingMeta stores meta of notes written before it was kept apart from them
+func addMissingMeta(tx *bbolt.Tx) error {
+	meta := tx.Bucket(metaBucket)
+
+	var missing []Note
+	err := tx.Bucket(notesBucket).ForEach(func(k, v []byte) error {
+		if meta.Get(k) != nil {
+			return nil
+		}
+		var n Note
+		if err := json.Unmarshal(v, &n); err != nil {
+			return fmt.Errorf("unmarshal note %s: %w", k, err)
+		}
+		missing = append(missing, n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, n := range missing {
+		if err := putMeta(tx, n.meta()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the database file
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func (s *Storage) CreateNote(ctx context.Context, sn creating.SecureNote) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(notesBucket).Get([]byte(sn.ID)) != nil {
+			return creating.ErrIDTaken
+		}
+		return putNote(tx, toNote(sn))
+	})
+	if errors.Is(err, creating.ErrIDTaken) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("put note in db: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementNoteCounter uses the bucket sequence, which bbolt increments within a serialized write transaction
+func (s *Storage) IncrementNoteCounter(ctx context.Context) (int, error) {
+	var counter uint64
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		counter, err = tx.Bucket(notesBucket).NextSequence()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update note counter: %w", err)
+	}
+
+	return int(counter), nil
+}
+
+func (s *Storage) GetNote(ctx context.Context, noteID string) (getting.SecureNote, error) {
+	var n Note
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return getReadableNote(tx, noteID, &n)
+	})
+	if err != nil {
+		return getting.SecureNote{}, err
+	}
+
+	return n.toGetting(), nil
+}
+
+// GetNoteMeta reads the meta bucket only, so note content is never loaded
+func (s *Storage) GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error) {
+	var m noteMeta
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(metaBucket).Get([]byte(noteID))
+		if value == nil {
+			return getting.ErrNotFound
+		}
+		if err := json.Unmarshal(value, &m); err != nil {
+			return fmt.Errorf("unmarshal note meta from db: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return getting.SecureNoteMeta{}, err
+	}
+	if m.Consumed {
+		return getting.SecureNoteMeta{}, getting.ErrNotFound
+	}
+
+	return m.toGetting(), nil
+}
+
+// ConsumeRead atomically decrements reads left of a note and drops its content with the last read.
+// Notes without a read budget cannot be consumed and are reported as getting.ErrNotFound.
+func (s *Storage) ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error) {
+	var read getting.SecureNote
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		var n Note
+		if err := getReadableNote(tx, noteID, &n); err != nil {
+			return err
+		}
+		if n.ReadsLeft < 1 {
+			return getting.ErrNotFound
+		}
+		n.ReadsLeft--
+		n.ReadCount++
+		n.LastReadAt = at.Unix()
+		read = n.toGetting()
+		if n.ReadsLeft == 0 {
+			n.consume()
+		}
+		return putNote(tx, n)
+	})
+	if err != nil {
+		return getting.SecureNote{}, err
+	}
+
+	return read, nil
+}
+
+func (s *Storage) RecordRead(ctx context.Context, noteID string, at time.Time) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		var n Note
+		if err := getReadableNote(tx, noteID, &n); err != nil {
+			return err
+		}
+		n.ReadCount++
+		n.LastReadAt = at.Unix()
+		return putNote(tx, n)
+	})
+}
+
+func (s *Storage) DeleteNote(ctx context.Context, noteID string) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return deleteNote(tx, []byte(noteID))
+	})
+	if err != nil {
+		return fmt.Errorf("delete note from db: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error) {
+	var n Note
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if err := getReadableNote(tx, noteID, &n); err != nil {
+			return err
+		}
+		if !a.Accepts(n.FailedAttempts, n.LastFailedAt) {
+			return getting.ErrAttemptRejected
+		}
+		n.FailedAttempts++
+		n.LastFailedAt = a.At.Unix()
+		return putNote(tx, n)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n.FailedAttempts, nil
+}
+
+func (s *Storage) ClearFailedAttempts(ctx context.Context, noteID string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		var n Note
+		if err := getReadableNote(tx, noteID, &n); err != nil {
+			return err
+		}
+		n.FailedAttempts = 0
+		n.LastFailedAt = 0
+		return putNote(tx, n)
+	})
+}
+
+func (s *Storage) GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error) {
+	var n Note
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return getNote(tx, noteID, &n)
+	})
+	if errors.Is(err, getting.ErrNotFound) {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+	if err != nil {
+		return managing.SecureNoteStatus{}, err
+	}
+
+	return n.toManagingStatus(), nil
+}
+
+func (s *Storage) UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error) {
+	var n Note
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if err := getReadableNote(tx, noteID, &n); err != nil {
+			return err
+		}
+		if u.TTL != nil {
+			n.TTL = *u.TTL
+		}
+		if u.ReadsLeft != nil {
+			n.ReadsLeft = *u.ReadsLeft
+		}
+		return putNote(tx, n)
+	})
+	if errors.Is(err, getting.ErrNotFound) {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+	if err != nil {
+		return managing.SecureNoteStatus{}, err
+	}
+
+	return n.toManagingStatus(), nil
+}
+
+// DeleteExpired removes notes whose TTL has passed
+func (s *Storage) DeleteExpired(ctx context.Context) (deleted int, err error) {
+	now := s.now().Unix()
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		var expired [][]byte
+		err := tx.Bucket(metaBucket).ForEach(func(k, v []byte) error {
+			var m noteMeta
+			if err := json.Unmarshal(v, &m); err != nil {
+				return fmt.Errorf("unmarshal note meta %s: %w", k, err)
+			}
+			if m.TTL <= now {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys are deleted after iteration, because modifying a bucket while iterating it is unsafe
+		for _, k := range expired {
+			if err := deleteNote(tx, k); err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("delete expired notes: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// putNote stores a note together with its meta
+func putNote(tx *bbolt.Tx, n Note) error {
+	value, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshal note: %w", err)
+	}
+	if err := tx.Bucket(notesBucket).Put([]byte(n.ID), value); err != nil {
+		return err
+	}
+	return putMeta(tx, n.meta())
+}
+
+func putMeta(tx *bbolt.Tx, m noteMeta) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal note meta: %w", err)
+	}
+	return tx.Bucket(metaBucket).Put([]byte(m.ID), value)
+}
+
+// deleteNote removes a note together with its meta
+func deleteNote(tx *bbolt.Tx, key []byte) error {
+	if err := tx.Bucket(notesBucket).Delete(key); err != nil {
+		return err
+	}
+	return tx.Bucket(metaBucket).Delete(key)
+}
+
+func getNote(tx *bbolt.Tx, noteID string, n *Note) error {
+	value := tx.Bucket(notesBucket).Get([]byte(noteID))
+	if value == nil {
+		return getting.ErrNotFound
+	}
+
+	if err := json.Unmarshal(value, n); err != nil {
+		return fmt.Errorf("unmarshal note from db: %w", err)
+	}
+
+	return nil
+}
+
+// getReadableNote is getNote for notes that were not consumed yet
+func getReadableNote(tx *bbolt.Tx, noteID string, n *Note) error {
+	if err := getNote(tx, noteID, n); err != nil {
+		return err
+	}
+	if n.Consumed {
+		return getting.ErrNotFound
+	}
+	return nil
+}
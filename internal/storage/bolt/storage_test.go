@@ -0,0 +1,143 @@
+package bolt_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/storage/bolt"
+	"github.com/projects/secure-notes/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return openStorage(t, time.Now)
+	})
+}
+
+func TestStorage_DeleteExpired(t *testing.T) {
+	// given
+	now := time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC)
+	s := openStorage(t, func() time.Time { return now })
+
+	for _, id := range []string{"expired1", "expired2", "expired3"} {
+		require.NoError(t, s.CreateNote(context.TODO(), creating.SecureNote{ID: id, TTL: now.Add(-time.Second).Unix()}))
+	}
+	require.NoError(t, s.CreateNote(context.TODO(), creating.SecureNote{ID: "alive", TTL: now.Add(time.Hour).Unix()}))
+
+	// when
+	gotDeleted, gotErr := s.DeleteExpired(context.TODO())
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, 3, gotDeleted)
+
+	_, err := s.GetNote(context.TODO(), "expired2")
+	assert.True(t, errors.Is(err, getting.ErrNotFound))
+
+	_, err = s.GetNote(context.TODO(), "alive")
+	assert.NoError(t, err)
+}
+
+func TestStorage_CounterSurvivesReopen(t *testing.T) {
+	// given
+	path := filepath.Join(tempDir(t), "notes.db")
+	s, err := bolt.Open(path, time.Now)
+	require.NoError(t, err)
+	_, _ = s.IncrementNoteCounter(context.TODO())
+	_, _ = s.IncrementNoteCounter(context.TODO())
+	require.NoError(t, s.Close())
+
+	// when
+	s, err = bolt.Open(path, time.Now)
+	require.NoError(t, err)
+	defer s.Close()
+	gotCounter, gotErr := s.IncrementNoteCounter(context.TODO())
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, 3, gotCounter)
+}
+
+func TestStorage_GetNoteMetaDoesNotDecodeContent(t *testing.T) {
+	// given
+	path := filepath.Join(tempDir(t), "notes.db")
+	s, err := bolt.Open(path, time.Now)
+	require.NoError(t, err)
+	ttl := time.Now().Add(time.Hour).Unix()
+	require.NoError(t, s.CreateNote(context.TODO(), creating.SecureNote{ID: "qx2rx", TTL: ttl, MaxReads: 2}))
+	require.NoError(t, s.Close())
+	putRaw(t, path, "notes", "qx2rx", "not a note")
+
+	s, err = bolt.Open(path, time.Now)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// when
+	gotMeta, gotErr := s.GetNoteMeta(context.TODO(), "qx2rx")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, getting.SecureNoteMeta{ID: "qx2rx", TTL: ttl, ReadsLeft: 2}, gotMeta)
+	_, err = s.GetNote(context.TODO(), "qx2rx")
+	assert.Error(t, err)
+}
+
+func TestStorage_OpenAddsMissingMeta(t *testing.T) {
+	// given
+	path := filepath.Join(tempDir(t), "notes.db")
+	putRaw(t, path, "notes", "qx2rx", `{"pk":"qx2rx","ttl":1584892800,"readsLeft":1,"opaque":true,"ciphertext":"abc"}`)
+
+	// when
+	s, err := bolt.Open(path, time.Now)
+	require.NoError(t, err)
+	defer s.Close()
+	gotMeta, gotErr := s.GetNoteMeta(context.TODO(), "qx2rx")
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, getting.SecureNoteMeta{ID: "qx2rx", TTL: 1584892800, ReadsLeft: 1, Opaque: true}, gotMeta)
+}
+
+// putRaw writes value under key of bucket in the database file at path, bypassing Storage
+func putRaw(t *testing.T, path, bucket, key, value string) {
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), []byte(value))
+	})
+	require.NoError(t, err)
+}
+
+func openStorage(t *testing.T, now func() time.Time) *bolt.Storage {
+	s, err := bolt.Open(filepath.Join(tempDir(t), "notes.db"), now)
+	if err != nil {
+		t.Fatalf("open bolt storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "secure-notes")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
@@ -1,10 +1,177 @@
 package dynamodb
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/kms"
+	"github.com/projects/secure-notes/internal/platform/security"
+)
+
 // Note defines properties of a secured note that is persisted in storage
 type Note struct {
-	ID          string `dynamodbav:"pk"`
-	Text        string `dynamodbav:"text"`
-	Hash        string `dynamodbav:"hash"`
-	TTL         int64  `dynamodbav:"ttl"`
-	OneTimeRead bool   `dynamodbav:"oneTimeRead"`
+	ID         string            `dynamodbav:"pk"`
+	Sealed     *sealedText       `dynamodbav:"sealed,omitempty"`
+	TTL        int64             `dynamodbav:"ttl"`
+	ReadsLeft  int               `dynamodbav:"readsLeft,omitempty"`
+	Opaque     bool              `dynamodbav:"opaque,omitempty"`
+	Ciphertext string            `dynamodbav:"ciphertext,omitempty"`
+	Metadata   map[string]string `dynamodbav:"metadata,omitempty"`
+	// Envelope replaces Sealed, Ciphertext, Metadata and WebhookSecret when storage has a key manager
+	Envelope *envelope `dynamodbav:"envelope,omitempty"`
+
+	MaxFailedAttempts int   `dynamodbav:"maxFailedAttempts,omitempty"`
+	FailedAttempts    int   `dynamodbav:"failedAttempts,omitempty"`
+	LastFailedAt      int64 `dynamodbav:"lastFailedAt,omitempty"`
+
+	ManagementTokenHash string `dynamodbav:"managementTokenHash,omitempty"`
+	WebhookURL          string `dynamodbav:"webhookUrl,omitempty"`
+	WebhookSecret       string `dynamodbav:"webhookSecret,omitempty"`
+	ReadCount           int    `dynamodbav:"readCount,omitempty"`
+	LastReadAt          int64  `dynamodbav:"lastReadAt,omitempty"`
+	// Consumed notes had their last read taken and only keep their status until they expire
+	Consumed bool `dynamodbav:"consumed,omitempty"`
+
+	// OneTimeRead is only set on notes stored before read budgets and counts as one read left
+	OneTimeRead bool `dynamodbav:"oneTimeRead,omitempty"`
+}
+
+func (n Note) readsLeft() int {
+	if n.ReadsLeft == 0 && n.OneTimeRead {
+		return 1
+	}
+	return n.ReadsLeft
+}
+
+func (n Note) toManagingStatus() managing.SecureNoteStatus {
+	return managing.SecureNoteStatus{
+		ID:                  n.ID,
+		ManagementTokenHash: n.ManagementTokenHash,
+		TTL:                 n.TTL,
+		ReadsLeft:           n.readsLeft(),
+		ReadCount:           n.ReadCount,
+		LastReadAt:          n.LastReadAt,
+		Consumed:            n.Consumed,
+	}
+}
+
+// sealedText defines encrypted note text together with identifiers and parameters needed to open it
+type sealedText struct {
+	Cipher      string `dynamodbav:"cipher"`
+	KDF         string `dynamodbav:"kdf"`
+	KDFTime     uint32 `dynamodbav:"kdfTime"`
+	KDFMemory   uint32 `dynamodbav:"kdfMemory"`
+	KDFThreads  uint8  `dynamodbav:"kdfThreads"`
+	PepperKeyID string `dynamodbav:"pepperKeyId,omitempty"`
+	Salt        []byte `dynamodbav:"salt"`
+	Nonce       []byte `dynamodbav:"nonce"`
+	Ciphertext  []byte `dynamodbav:"ciphertext"`
+}
+
+func toSealedText(st security.SealedText) *sealedText {
+	return &sealedText{
+		Cipher:      st.Cipher,
+		KDF:         st.KDF,
+		KDFTime:     st.KDFParams.Time,
+		KDFMemory:   st.KDFParams.Memory,
+		KDFThreads:  st.KDFParams.Threads,
+		PepperKeyID: st.PepperKeyID,
+		Salt:        st.Salt,
+		Nonce:       st.Nonce,
+		Ciphertext:  st.Ciphertext,
+	}
+}
+
+func (st *sealedText) toSecurity() security.SealedText {
+	if st == nil {
+		return security.SealedText{}
+	}
+	return security.SealedText{
+		Cipher: st.Cipher,
+		KDF:    st.KDF,
+		KDFParams: security.KDFParams{
+			Time:    st.KDFTime,
+			Memory:  st.KDFMemory,
+			Threads: st.KDFThreads,
+		},
+		PepperKeyID: st.PepperKeyID,
+		Salt:        st.Salt,
+		Nonce:       st.Nonce,
+		Ciphertext:  st.Ciphertext,
+	}
+}
+
+// envelope holds note content encrypted under a data key, which is stored wrapped by a master key
+type envelope struct {
+	KeyID      string `dynamodbav:"keyId"`
+	WrappedKey []byte `dynamodbav:"wrappedKey"`
+	Nonce      []byte `dynamodbav:"nonce"`
+	Ciphertext []byte `dynamodbav:"ciphertext"`
+}
+
+// content lists attributes of a note that are kept in its envelope
+type content struct {
+	Sealed        *sealedText       `json:"sealed,omitempty"`
+	Ciphertext    string            `json:"ciphertext,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	WebhookSecret string            `json:"webhookSecret,omitempty"`
+}
+
+// seal moves content of n into an envelope bound to the note ID
+func (n *Note) seal(ctx context.Context, keys kms.KeyManager) error {
+	plain, err := json.Marshal(content{
+		Sealed:        n.Sealed,
+		Ciphertext:    n.Ciphertext,
+		Metadata:      n.Metadata,
+		WebhookSecret: n.WebhookSecret,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal note content: %w", err)
+	}
+
+	e, err := kms.Seal(ctx, keys, plain, []byte(n.ID))
+	if err != nil {
+		return fmt.Errorf("seal note content: %w", err)
+	}
+
+	n.Envelope = &envelope{
+		KeyID:      e.Key.KeyID,
+		WrappedKey: e.Key.Ciphertext,
+		Nonce:      e.Nonce,
+		Ciphertext: e.Ciphertext,
+	}
+	n.Sealed, n.Ciphertext, n.Metadata, n.WebhookSecret = nil, "", nil, ""
+	return nil
+}
+
+// open restores content of n from its envelope, if any
+func (n *Note) open(ctx context.Context, keys kms.KeyManager) error {
+	if n.Envelope == nil {
+		return nil
+	}
+	if keys == nil {
+		return fmt.Errorf("open note content: %w", kms.ErrUnknownKey)
+	}
+
+	plain, err := kms.Open(ctx, keys, n.Envelope.toKMS(), []byte(n.ID))
+	if err != nil {
+		return fmt.Errorf("open note content: %w", err)
+	}
+
+	var c content
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return fmt.Errorf("unmarshal note content: %w", err)
+	}
+	n.Sealed, n.Ciphertext, n.Metadata, n.WebhookSecret = c.Sealed, c.Ciphertext, c.Metadata, c.WebhookSecret
+	return nil
+}
+
+func (e *envelope) toKMS() kms.Envelope {
+	return kms.Envelope{
+		Key:        kms.WrappedKey{KeyID: e.KeyID, Ciphertext: e.WrappedKey},
+		Nonce:      e.Nonce,
+		Ciphertext: e.Ciphertext,
+	}
 }
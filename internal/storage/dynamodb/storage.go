@@ -2,35 +2,64 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/awserr"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
 	"github.com/projects/secure-notes/internal/creating"
 	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/kms"
+	"github.com/projects/secure-notes/internal/platform/logging"
 )
 
+// notConsumed guards writes that only make sense for notes that still have content
+const notConsumed = "attribute_exists(pk) AND attribute_not_exists(#consumed)"
+
 type Storage struct {
 	DbCli     *dynamodb.Client
 	TableName string
+	// Keys encrypt content of new notes with envelope encryption. Without them
+	// content is stored as is, and notes stored with an envelope cannot be read.
+	Keys kms.KeyManager
 }
 
-func NewStorage(dbCli *dynamodb.Client, tableName string) *Storage {
+func NewStorage(dbCli *dynamodb.Client, tableName string, keys kms.KeyManager) *Storage {
 	return &Storage{
 		DbCli:     dbCli,
 		TableName: tableName,
+		Keys:      keys,
 	}
 }
 
 func (s *Storage) CreateNote(ctx context.Context, sn creating.SecureNote) error {
 	newNote := Note{
-		ID:          sn.ID,
-		Text:        sn.Text,
-		Hash:        sn.Hash,
-		TTL:         sn.TTL,
-		OneTimeRead: sn.OneTimeRead,
+		ID:         sn.ID,
+		TTL:        sn.TTL,
+		ReadsLeft:  sn.MaxReads,
+		Opaque:     sn.Opaque,
+		Ciphertext: sn.Ciphertext,
+		Metadata:   sn.Metadata,
+
+		MaxFailedAttempts: sn.MaxFailedAttempts,
+
+		ManagementTokenHash: sn.ManagementTokenHash,
+		WebhookURL:          sn.WebhookURL,
+		WebhookSecret:       sn.WebhookSecret,
+	}
+	if !sn.Opaque {
+		newNote.Sealed = toSealedText(sn.Sealed)
+	}
+	if s.Keys != nil {
+		if err := newNote.seal(ctx, s.Keys); err != nil {
+			return err
+		}
 	}
 
 	item, err := dynamodbattribute.MarshalMap(newNote)
@@ -39,11 +68,16 @@ func (s *Storage) CreateNote(ctx context.Context, sn creating.SecureNote) error
 	}
 
 	input := dynamodb.PutItemInput{
-		Item:      item,
-		TableName: aws.String(s.TableName),
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		Item:                item,
+		TableName:           aws.String(s.TableName),
 	}
-	if _, err := s.DbCli.PutItemRequest(&input).Send(ctx); err != nil {
-		log.Print(err)
+	_, err = s.DbCli.PutItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return creating.ErrIDTaken
+	}
+	if err != nil {
+		logging.FromContext(ctx).Errorw("put note item", "table", s.TableName, "error", err)
 		return fmt.Errorf("put item in db: %w", err)
 	}
 
@@ -101,26 +135,489 @@ func (s *Storage) GetNote(ctx context.Context, noteID string) (getting.SecureNot
 		return getting.SecureNote{}, fmt.Errorf("get item from db: %w", err)
 	}
 
-	if notFound := len(item.Item) == 0; notFound {
+	if notFound := len(item.Item) == 0 || isConsumed(item.Item); notFound {
 		return getting.SecureNote{}, getting.ErrNotFound
 	}
 
+	return s.unmarshalGettingNote(ctx, item.Item)
+}
+
+// GetNoteMeta projects only metadata attributes, so note text never leaves the database
+func (s *Storage) GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error) {
+	input := dynamodb.GetItemInput{
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ProjectionExpression: aws.String("pk, #ttl, readsLeft, oneTimeRead, opaque, consumed"),
+		TableName:            aws.String(s.TableName),
+	}
+
+	item, err := s.DbCli.GetItemRequest(&input).Send(ctx)
+	if err != nil {
+		return getting.SecureNoteMeta{}, fmt.Errorf("get item meta from db: %w", err)
+	}
+
+	if notFound := len(item.Item) == 0 || isConsumed(item.Item); notFound {
+		return getting.SecureNoteMeta{}, getting.ErrNotFound
+	}
+
+	var n Note
+	if err := dynamodbattribute.UnmarshalMap(item.Item, &n); err != nil {
+		return getting.SecureNoteMeta{}, fmt.Errorf("unmarshal note meta from db map: %w", err)
+	}
+
+	meta := getting.SecureNoteMeta{
+		ID:        n.ID,
+		TTL:       n.TTL,
+		ReadsLeft: n.readsLeft(),
+		Opaque:    n.Opaque,
+	}
+
+	return meta, nil
+}
+
+// ConsumeRead atomically decrements reads left of a note and drops its content with the last read.
+// Both steps are conditional writes, so when several readers race for the last read
+// only one of them gets the note, others get getting.ErrNotFound.
+func (s *Storage) ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error) {
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#reads > :one"),
+		ExpressionAttributeNames: map[string]string{
+			"#reads":     "readsLeft",
+			"#readCount": "readCount",
+			"#lastRead":  "lastReadAt",
+		},
+		ExpressionAttributeValues: map[string]dynamodb.AttributeValue{
+			":one": {
+				N: aws.String("1"),
+			},
+			":at": {
+				N: aws.String(strconv.FormatInt(at.Unix(), 10)),
+			},
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ReturnValues:     dynamodb.ReturnValueAllNew,
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("set #reads = #reads - :one, #lastRead = :at add #readCount :one"),
+	}
+
+	resp, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return s.consumeLastRead(ctx, noteID, at)
+	}
+	if err != nil {
+		return getting.SecureNote{}, fmt.Errorf("conditional decrement reads left in db: %w", err)
+	}
+
+	return s.unmarshalGettingNote(ctx, resp.UpdateItemOutput.Attributes)
+}
+
+// consumeLastRead drops content of a note that has exactly one read left and returns its last state.
+// The note stays as a tombstone until it expires, so its creator can still see when it was read.
+func (s *Storage) consumeLastRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error) {
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#reads = :one OR (attribute_not_exists(#reads) AND #oneTimeRead = :true)"),
+		ExpressionAttributeNames: map[string]string{
+			"#reads":       "readsLeft",
+			"#oneTimeRead": "oneTimeRead",
+			"#readCount":   "readCount",
+			"#lastRead":    "lastReadAt",
+			"#consumed":    "consumed",
+			"#sealed":      "sealed",
+			"#hash":        "hash",
+			"#ciphertext":  "ciphertext",
+			"#metadata":    "metadata",
+			"#webhookUrl":  "webhookUrl",
+			"#webhookKey":  "webhookSecret",
+			"#envelope":    "envelope",
+		},
+		ExpressionAttributeValues: map[string]dynamodb.AttributeValue{
+			":one": {
+				N: aws.String("1"),
+			},
+			":true": {
+				BOOL: aws.Bool(true),
+			},
+			":at": {
+				N: aws.String(strconv.FormatInt(at.Unix(), 10)),
+			},
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ReturnValues:     dynamodb.ReturnValueAllOld,
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("set #consumed = :true, #lastRead = :at add #readCount :one remove #reads, #oneTimeRead, #sealed, #hash, #ciphertext, #metadata, #webhookUrl, #webhookKey, #envelope"),
+	}
+
+	resp, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return getting.SecureNote{}, getting.ErrNotFound
+	}
+	if err != nil {
+		return getting.SecureNote{}, fmt.Errorf("conditional consume last read in db: %w", err)
+	}
+
+	note, err := s.unmarshalGettingNote(ctx, resp.UpdateItemOutput.Attributes)
+	if err != nil {
+		return getting.SecureNote{}, err
+	}
+	note.ReadsLeft = 0
+
+	return note, nil
+}
+
+// RecordRead counts a read of a note without a read budget
+func (s *Storage) RecordRead(ctx context.Context, noteID string, at time.Time) error {
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String(notConsumed),
+		ExpressionAttributeNames: map[string]string{
+			"#consumed":  "consumed",
+			"#readCount": "readCount",
+			"#lastRead":  "lastReadAt",
+		},
+		ExpressionAttributeValues: map[string]dynamodb.AttributeValue{
+			":one": {
+				N: aws.String("1"),
+			},
+			":at": {
+				N: aws.String(strconv.FormatInt(at.Unix(), 10)),
+			},
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("set #lastRead = :at add #readCount :one"),
+	}
+
+	_, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return getting.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update read count: %w", err)
+	}
+
+	return nil
+}
+
+// ReserveAttempt atomically increments failed attempts unless the note reached its limit or a newer
+// failure restarted its backoff, so parallel guesses cannot outrun either of them
+func (s *Storage) ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error) {
+	input := dynamodb.UpdateItemInput{
+		// attributes of notes without failed attempts are absent rather than zero
+		ConditionExpression: aws.String(notConsumed +
+			" AND (attribute_not_exists(#failed) OR #failed < :limit)" +
+			" AND (attribute_not_exists(#last) OR #last <= :lastBy)"),
+		ExpressionAttributeNames: map[string]string{
+			"#consumed": "consumed",
+			"#failed":   "failedAttempts",
+			"#last":     "lastFailedAt",
+		},
+		ExpressionAttributeValues: map[string]dynamodb.AttributeValue{
+			":one": {
+				N: aws.String("1"),
+			},
+			":limit": {
+				N: aws.String(strconv.Itoa(a.Limit)),
+			},
+			":lastBy": {
+				N: aws.String(strconv.FormatInt(a.LastFailedBy, 10)),
+			},
+			":at": {
+				N: aws.String(strconv.FormatInt(a.At.Unix(), 10)),
+			},
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ReturnValues:     dynamodb.ReturnValueUpdatedNew,
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("add #failed :one set #last = :at"),
+	}
+
+	resp, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		// the condition also fails for missing notes, which must not look like a rejected guess
+		if _, err := s.GetNoteMeta(ctx, noteID); err != nil {
+			return 0, err
+		}
+		return 0, getting.ErrAttemptRejected
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reserve failed attempt: %w", err)
+	}
+
+	var n Note
+	if err := dynamodbattribute.UnmarshalMap(resp.UpdateItemOutput.Attributes, &n); err != nil {
+		return 0, fmt.Errorf("unmarshal failed attempts from db map: %w", err)
+	}
+
+	return n.FailedAttempts, nil
+}
+
+// ClearFailedAttempts removes failed attempts of a note whose password was verified
+func (s *Storage) ClearFailedAttempts(ctx context.Context, noteID string) error {
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String(notConsumed),
+		ExpressionAttributeNames: map[string]string{
+			"#consumed": "consumed",
+			"#failed":   "failedAttempts",
+			"#last":     "lastFailedAt",
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("remove #failed, #last"),
+	}
+
+	_, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return getting.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("clear failed attempts: %w", err)
+	}
+
+	return nil
+}
+
+// GetNoteStatus loads a note including consumed ones, but without its content
+func (s *Storage) GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error) {
+	input := dynamodb.GetItemInput{
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ProjectionExpression: aws.String("pk, managementTokenHash, #ttl, readsLeft, oneTimeRead, readCount, lastReadAt, consumed"),
+		TableName:            aws.String(s.TableName),
+	}
+
+	item, err := s.DbCli.GetItemRequest(&input).Send(ctx)
+	if err != nil {
+		return managing.SecureNoteStatus{}, fmt.Errorf("get item status from db: %w", err)
+	}
+
+	if notFound := len(item.Item) == 0; notFound {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+
 	var n Note
 	if err := dynamodbattribute.UnmarshalMap(item.Item, &n); err != nil {
+		return managing.SecureNoteStatus{}, fmt.Errorf("unmarshal note status from db map: %w", err)
+	}
+
+	return n.toManagingStatus(), nil
+}
+
+// UpdateNote changes TTL and reads left of a note that was not consumed yet
+func (s *Storage) UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error) {
+	names := map[string]string{
+		"#consumed": "consumed",
+	}
+	values := map[string]dynamodb.AttributeValue{}
+	var set, remove []string
+
+	if u.TTL != nil {
+		names["#ttl"] = "ttl"
+		values[":ttl"] = dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(*u.TTL, 10))}
+		set = append(set, "#ttl = :ttl")
+	}
+	if u.ReadsLeft != nil {
+		names["#reads"] = "readsLeft"
+		names["#oneTimeRead"] = "oneTimeRead"
+		remove = append(remove, "#oneTimeRead")
+		if *u.ReadsLeft > 0 {
+			values[":reads"] = dynamodb.AttributeValue{N: aws.String(strconv.Itoa(*u.ReadsLeft))}
+			set = append(set, "#reads = :reads")
+		} else {
+			remove = append(remove, "#reads")
+		}
+	}
+
+	var update []string
+	if len(set) > 0 {
+		update = append(update, "set "+strings.Join(set, ", "))
+	}
+	if len(remove) > 0 {
+		update = append(update, "remove "+strings.Join(remove, ", "))
+	}
+
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression:      aws.String(notConsumed),
+		ExpressionAttributeNames: names,
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(noteID),
+			},
+		},
+		ReturnValues:     dynamodb.ReturnValueAllNew,
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String(strings.Join(update, " ")),
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+
+	resp, err := s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+	if err != nil {
+		return managing.SecureNoteStatus{}, fmt.Errorf("update note in db: %w", err)
+	}
+
+	var n Note
+	if err := dynamodbattribute.UnmarshalMap(resp.UpdateItemOutput.Attributes, &n); err != nil {
+		return managing.SecureNoteStatus{}, fmt.Errorf("unmarshal note status from db map: %w", err)
+	}
+
+	return n.toManagingStatus(), nil
+}
+
+// RewrapDataKeys wraps data keys of all notes stored with an envelope by the current master key
+// of Keys, so older master keys can be retired. Note content is never decrypted. Notes read or
+// deleted in the meantime are skipped.
+func (s *Storage) RewrapDataKeys(ctx context.Context) (rewrapped int, err error) {
+	input := dynamodb.ScanInput{
+		ExpressionAttributeNames: map[string]string{
+			"#envelope": "envelope",
+		},
+		FilterExpression:     aws.String("attribute_exists(#envelope)"),
+		ProjectionExpression: aws.String("pk, #envelope"),
+		TableName:            aws.String(s.TableName),
+	}
+
+	p := dynamodb.NewScanPaginator(s.DbCli.ScanRequest(&input))
+	for p.Next(ctx) {
+		var notes []Note
+		if err := dynamodbattribute.UnmarshalListOfMaps(p.CurrentPage().Items, &notes); err != nil {
+			return rewrapped, fmt.Errorf("unmarshal notes from db maps: %w", err)
+		}
+
+		for _, n := range notes {
+			ok, err := s.rewrapDataKey(ctx, n)
+			if err != nil {
+				return rewrapped, fmt.Errorf("rewrap data key of note %s: %w", n.ID, err)
+			}
+			if ok {
+				rewrapped++
+			}
+		}
+	}
+	if err := p.Err(); err != nil {
+		return rewrapped, fmt.Errorf("scan notes with envelope: %w", err)
+	}
+
+	return rewrapped, nil
+}
+
+// rewrapDataKey replaces the wrapped data key of a note unless the envelope changed meanwhile
+func (s *Storage) rewrapDataKey(ctx context.Context, n Note) (bool, error) {
+	wrapped, err := s.Keys.ReEncrypt(ctx, n.Envelope.toKMS().Key)
+	if err != nil {
+		return false, err
+	}
+
+	input := dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#envelope.#wrappedKey = :old"),
+		ExpressionAttributeNames: map[string]string{
+			"#envelope":   "envelope",
+			"#keyId":      "keyId",
+			"#wrappedKey": "wrappedKey",
+		},
+		ExpressionAttributeValues: map[string]dynamodb.AttributeValue{
+			":old": {
+				B: n.Envelope.WrappedKey,
+			},
+			":keyId": {
+				S: aws.String(wrapped.KeyID),
+			},
+			":wrappedKey": {
+				B: wrapped.Ciphertext,
+			},
+		},
+		Key: map[string]dynamodb.AttributeValue{
+			"pk": {
+				S: aws.String(n.ID),
+			},
+		},
+		TableName:        aws.String(s.TableName),
+		UpdateExpression: aws.String("set #envelope.#keyId = :keyId, #envelope.#wrappedKey = :wrappedKey"),
+	}
+
+	_, err = s.DbCli.UpdateItemRequest(&input).Send(ctx)
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update wrapped data key in db: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Storage) unmarshalGettingNote(ctx context.Context, item map[string]dynamodb.AttributeValue) (getting.SecureNote, error) {
+	var n Note
+	if err := dynamodbattribute.UnmarshalMap(item, &n); err != nil {
 		return getting.SecureNote{}, fmt.Errorf("unmarshal note from db map: %w", err)
 	}
+	if err := n.open(ctx, s.Keys); err != nil {
+		return getting.SecureNote{}, err
+	}
 
 	note := getting.SecureNote{
-		ID:          n.ID,
-		Text:        n.Text,
-		Hash:        n.Hash,
-		TTL:         n.TTL,
-		OneTimeRead: n.OneTimeRead,
+		ID:         n.ID,
+		Sealed:     n.Sealed.toSecurity(),
+		TTL:        n.TTL,
+		ReadsLeft:  n.readsLeft(),
+		Opaque:     n.Opaque,
+		Ciphertext: n.Ciphertext,
+		Metadata:   n.Metadata,
+
+		MaxFailedAttempts: n.MaxFailedAttempts,
+		FailedAttempts:    n.FailedAttempts,
+		LastFailedAt:      n.LastFailedAt,
+
+		WebhookURL:    n.WebhookURL,
+		WebhookSecret: n.WebhookSecret,
 	}
 
 	return note, nil
 }
 
+func isConsumed(item map[string]dynamodb.AttributeValue) bool {
+	v, ok := item["consumed"]
+	return ok && v.BOOL != nil && *v.BOOL
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
 func (s *Storage) DeleteNote(ctx context.Context, noteID string) error {
 	input := dynamodb.DeleteItemInput{
 		Key: map[string]dynamodb.AttributeValue{
@@ -0,0 +1,160 @@
+package dynamodb_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/external"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/platform/kms"
+	db "github.com/projects/secure-notes/internal/storage/dynamodb"
+	"github.com/projects/secure-notes/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage_Conformance runs against DynamoDB Local, e.g.
+// docker run -p 8000:8000 amazon/dynamodb-local
+// DYNAMODB_ENDPOINT=http://localhost:8000 go test ./internal/storage/dynamodb
+func TestStorage_Conformance(t *testing.T) {
+	dbCli := dynamoClient(t)
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return db.NewStorage(dbCli, newTable(t, dbCli), nil)
+	})
+}
+
+func TestStorage_ConformanceWithEnvelope(t *testing.T) {
+	dbCli := dynamoClient(t)
+	keys := localKeys(t, "k1")
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return db.NewStorage(dbCli, newTable(t, dbCli), keys)
+	})
+}
+
+func TestStorage_EnvelopeHidesContent(t *testing.T) {
+	// given
+	dbCli := dynamoClient(t)
+	tableName := newTable(t, dbCli)
+	s := db.NewStorage(dbCli, tableName, localKeys(t, "k1"))
+
+	// when
+	err := s.CreateNote(context.TODO(), creating.SecureNote{
+		ID:            "qx2rx",
+		Opaque:        true,
+		Ciphertext:    "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:      map[string]string{"alg": "AES-GCM"},
+		WebhookSecret: "whsec",
+		TTL:           time.Now().Add(time.Hour).Unix(),
+	})
+
+	// then
+	require.NoError(t, err)
+	item := rawItem(t, dbCli, tableName, "qx2rx")
+	assert.NotContains(t, item, "ciphertext")
+	assert.NotContains(t, item, "metadata")
+	assert.NotContains(t, item, "webhookSecret")
+	assert.Contains(t, item, "envelope")
+
+	_, err = db.NewStorage(dbCli, tableName, nil).GetNote(context.TODO(), "qx2rx")
+	assert.Error(t, err, "notes with an envelope cannot be read without keys")
+}
+
+func TestStorage_RewrapDataKeys(t *testing.T) {
+	// given
+	dbCli := dynamoClient(t)
+	tableName := newTable(t, dbCli)
+	before := db.NewStorage(dbCli, tableName, localKeys(t, "k1"))
+	for _, id := range []string{"qx2rx", "k7QbX"} {
+		require.NoError(t, before.CreateNote(context.TODO(), creating.SecureNote{
+			ID:         id,
+			Opaque:     true,
+			Ciphertext: "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+			TTL:        time.Now().Add(time.Hour).Unix(),
+		}))
+	}
+	after := db.NewStorage(dbCli, tableName, localKeys(t, "k2", "k1"))
+
+	// when
+	rewrapped, err := after.RewrapDataKeys(context.TODO())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, 2, rewrapped)
+
+	retired := db.NewStorage(dbCli, tableName, localKeys(t, "k2"))
+	got, err := retired.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=", got.Ciphertext)
+}
+
+func dynamoClient(t *testing.T) *dynamodb.Client {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT not set")
+	}
+
+	cfg, err := external.LoadDefaultAWSConfig()
+	if err != nil {
+		t.Fatalf("load AWS config: %v", err)
+	}
+	cfg.EndpointResolver = aws.ResolveWithEndpointURL(endpoint)
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return dynamodb.New(cfg)
+}
+
+// localKeys provides a local key manager with master keys derived from their IDs; the first is current
+func localKeys(t *testing.T, ids ...string) *kms.Local {
+	keys := make(map[string][]byte)
+	for _, id := range ids {
+		keys[id] = []byte(fmt.Sprintf("%-32s", id))
+	}
+	km, err := kms.NewLocal(keys, ids[0])
+	require.NoError(t, err)
+	return km
+}
+
+func rawItem(t *testing.T, dbCli *dynamodb.Client, tableName, noteID string) map[string]dynamodb.AttributeValue {
+	input := dynamodb.GetItemInput{
+		Key:       map[string]dynamodb.AttributeValue{"pk": {S: aws.String(noteID)}},
+		TableName: aws.String(tableName),
+	}
+	resp, err := dbCli.GetItemRequest(&input).Send(context.TODO())
+	require.NoError(t, err)
+	return resp.Item
+}
+
+func newTable(t *testing.T, dbCli *dynamodb.Client) string {
+	tableName := fmt.Sprintf("notes-%d", time.Now().UnixNano())
+	createTable(t, dbCli, tableName)
+	return tableName
+}
+
+func createTable(t *testing.T, dbCli *dynamodb.Client, tableName string) {
+	input := dynamodb.CreateTableInput{
+		AttributeDefinitions: []dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: dynamodb.ScalarAttributeTypeS},
+		},
+		KeySchema: []dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: dynamodb.KeyTypeHash},
+		},
+		BillingMode: dynamodb.BillingModePayPerRequest,
+		TableName:   aws.String(tableName),
+	}
+	if _, err := dbCli.CreateTableRequest(&input).Send(context.TODO()); err != nil {
+		t.Fatalf("create table %s: %v", tableName, err)
+	}
+
+	t.Cleanup(func() {
+		input := dynamodb.DeleteTableInput{TableName: aws.String(tableName)}
+		_, _ = dbCli.DeleteTableRequest(&input).Send(context.TODO())
+	})
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/security"
+)
+
+// Note defines properties of a secured note that is kept in memory
+type Note struct {
+	ID         string
+	Sealed     security.SealedText
+	TTL        int64
+	ReadsLeft  int
+	Opaque     bool
+	Ciphertext string
+	Metadata   map[string]string
+
+	MaxFailedAttempts int
+	FailedAttempts    int
+	LastFailedAt      int64
+
+	ManagementTokenHash string
+	WebhookURL          string
+	WebhookSecret       string
+	ReadCount           int
+	LastReadAt          int64
+	// Consumed notes had their last read taken and only keep their status until they expire
+	Consumed bool
+}
+
+func toNote(sn creating.SecureNote) Note {
+	return Note{
+		ID:         sn.ID,
+		Sealed:     sn.Sealed,
+		TTL:        sn.TTL,
+		ReadsLeft:  sn.MaxReads,
+		Opaque:     sn.Opaque,
+		Ciphertext: sn.Ciphertext,
+		Metadata:   sn.Metadata,
+
+		MaxFailedAttempts: sn.MaxFailedAttempts,
+
+		ManagementTokenHash: sn.ManagementTokenHash,
+		WebhookURL:          sn.WebhookURL,
+		WebhookSecret:       sn.WebhookSecret,
+	}
+}
+
+// consume drops note content, so only its status remains
+func (n *Note) consume() {
+	n.Sealed = security.SealedText{}
+	n.Ciphertext = ""
+	n.Metadata = nil
+	n.WebhookURL = ""
+	n.WebhookSecret = ""
+	n.Consumed = true
+}
+
+func (n Note) toGetting() getting.SecureNote {
+	return getting.SecureNote{
+		ID:         n.ID,
+		Sealed:     n.Sealed,
+		TTL:        n.TTL,
+		ReadsLeft:  n.ReadsLeft,
+		Opaque:     n.Opaque,
+		Ciphertext: n.Ciphertext,
+		Metadata:   n.Metadata,
+
+		MaxFailedAttempts: n.MaxFailedAttempts,
+		FailedAttempts:    n.FailedAttempts,
+		LastFailedAt:      n.LastFailedAt,
+
+		WebhookURL:    n.WebhookURL,
+		WebhookSecret: n.WebhookSecret,
+	}
+}
+
+// noteMeta is kept apart from Note, so describing a note never touches its content
+type noteMeta struct {
+	ID        string
+	TTL       int64
+	ReadsLeft int
+	Opaque    bool
+	Consumed  bool
+}
+
+func (n Note) meta() noteMeta {
+	return noteMeta{
+		ID:        n.ID,
+		TTL:       n.TTL,
+		ReadsLeft: n.ReadsLeft,
+		Opaque:    n.Opaque,
+		Consumed:  n.Consumed,
+	}
+}
+
+func (m noteMeta) toGetting() getting.SecureNoteMeta {
+	return getting.SecureNoteMeta{
+		ID:        m.ID,
+		TTL:       m.TTL,
+		ReadsLeft: m.ReadsLeft,
+		Opaque:    m.Opaque,
+	}
+}
+
+func (n Note) toManagingStatus() managing.SecureNoteStatus {
+	return managing.SecureNoteStatus{
+		ID:                  n.ID,
+		ManagementTokenHash: n.ManagementTokenHash,
+		TTL:                 n.TTL,
+		ReadsLeft:           n.ReadsLeft,
+		ReadCount:           n.ReadCount,
+		LastReadAt:          n.LastReadAt,
+		Consumed:            n.Consumed,
+	}
+}
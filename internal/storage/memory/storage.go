@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+)
+
+// Storage keeps notes in process memory. It is meant for local development and tests.
+type Storage struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	counter int
+	notes   map[string]Note
+	metas   map[string]noteMeta
+}
+
+// NewStorage provides empty in-memory storage
+func NewStorage(now func() time.Time) *Storage {
+	return &Storage{
+		now:   now,
+		notes: make(map[string]Note),
+		metas: make(map[string]noteMeta),
+	}
+}
+
+func (s *Storage) CreateNote(ctx context.Context, sn creating.SecureNote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notes[sn.ID]; ok {
+		return creating.ErrIDTaken
+	}
+	s.put(toNote(sn))
+	return nil
+}
+
+func (s *Storage) IncrementNoteCounter(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counter++
+	return s.counter, nil
+}
+
+func (s *Storage) GetNote(ctx context.Context, noteID string) (getting.SecureNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok {
+		return getting.SecureNote{}, getting.ErrNotFound
+	}
+
+	return n.toGetting(), nil
+}
+
+func (s *Storage) GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.metas[noteID]
+	if !ok || m.Consumed {
+		return getting.SecureNoteMeta{}, getting.ErrNotFound
+	}
+
+	return m.toGetting(), nil
+}
+
+// ConsumeRead atomically decrements reads left of a note and drops its content with the last read.
+// Notes without a read budget cannot be consumed and are reported as getting.ErrNotFound.
+func (s *Storage) ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok || n.ReadsLeft < 1 {
+		return getting.SecureNote{}, getting.ErrNotFound
+	}
+	n.ReadsLeft--
+	n.ReadCount++
+	n.LastReadAt = at.Unix()
+	read := n.toGetting()
+	if n.ReadsLeft == 0 {
+		n.consume()
+	}
+	s.put(n)
+
+	return read, nil
+}
+
+func (s *Storage) RecordRead(ctx context.Context, noteID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok {
+		return getting.ErrNotFound
+	}
+	n.ReadCount++
+	n.LastReadAt = at.Unix()
+	s.put(n)
+
+	return nil
+}
+
+func (s *Storage) DeleteNote(ctx context.Context, noteID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(noteID)
+	return nil
+}
+
+func (s *Storage) ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok {
+		return 0, getting.ErrNotFound
+	}
+	if !a.Accepts(n.FailedAttempts, n.LastFailedAt) {
+		return 0, getting.ErrAttemptRejected
+	}
+	n.FailedAttempts++
+	n.LastFailedAt = a.At.Unix()
+	s.put(n)
+
+	return n.FailedAttempts, nil
+}
+
+func (s *Storage) ClearFailedAttempts(ctx context.Context, noteID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok {
+		return getting.ErrNotFound
+	}
+	n.FailedAttempts = 0
+	n.LastFailedAt = 0
+	s.put(n)
+
+	return nil
+}
+
+func (s *Storage) GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notes[noteID]
+	if !ok {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+
+	return n.toManagingStatus(), nil
+}
+
+func (s *Storage) UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.readable(noteID)
+	if !ok {
+		return managing.SecureNoteStatus{}, managing.ErrNotFound
+	}
+	if u.TTL != nil {
+		n.TTL = *u.TTL
+	}
+	if u.ReadsLeft != nil {
+		n.ReadsLeft = *u.ReadsLeft
+	}
+	s.put(n)
+
+	return n.toManagingStatus(), nil
+}
+
+// put stores a note together with its meta. Callers must hold the lock.
+func (s *Storage) put(n Note) {
+	s.notes[n.ID] = n
+	s.metas[n.ID] = n.meta()
+}
+
+// remove deletes a note together with its meta. Callers must hold the lock.
+func (s *Storage) remove(noteID string) {
+	delete(s.notes, noteID)
+	delete(s.metas, noteID)
+}
+
+// readable returns a note unless it is missing or consumed. Callers must hold the lock.
+func (s *Storage) readable(noteID string) (Note, bool) {
+	n, ok := s.notes[noteID]
+	if !ok || n.Consumed {
+		return Note{}, false
+	}
+	return n, true
+}
+
+// DeleteExpired removes notes whose TTL has passed, like DynamoDB TTL sweeps do.
+// Until then expired notes remain readable from storage and getting.Service filters them out.
+func (s *Storage) DeleteExpired(ctx context.Context) (deleted int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().Unix()
+	for id, m := range s.metas {
+		if m.TTL <= now {
+			s.remove(id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
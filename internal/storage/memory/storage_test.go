@@ -0,0 +1,42 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/storage/memory"
+	"github.com/projects/secure-notes/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return memory.NewStorage(time.Now)
+	})
+}
+
+func TestStorage_DeleteExpired(t *testing.T) {
+	// given
+	now := time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC)
+	s := memory.NewStorage(func() time.Time { return now })
+
+	_ = s.CreateNote(context.TODO(), creating.SecureNote{ID: "expired", TTL: now.Add(-time.Second).Unix()})
+	_ = s.CreateNote(context.TODO(), creating.SecureNote{ID: "alive", TTL: now.Add(time.Hour).Unix()})
+
+	// when
+	gotDeleted, gotErr := s.DeleteExpired(context.TODO())
+
+	// then
+	assert.NoError(t, gotErr)
+	assert.Equal(t, 1, gotDeleted)
+
+	_, err := s.GetNote(context.TODO(), "expired")
+	assert.True(t, errors.Is(err, getting.ErrNotFound))
+
+	_, err = s.GetNote(context.TODO(), "alive")
+	assert.NoError(t, err)
+}
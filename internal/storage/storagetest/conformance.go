@@ -0,0 +1,530 @@
+// Package storagetest provides a conformance test suite every note storage backend must pass.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/creating"
+	"github.com/projects/secure-notes/internal/getting"
+	"github.com/projects/secure-notes/internal/managing"
+	"github.com/projects/secure-notes/internal/platform/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Repository is the union of repositories required by creating, getting and managing services
+type Repository interface {
+	CreateNote(ctx context.Context, sn creating.SecureNote) error
+	IncrementNoteCounter(ctx context.Context) (int, error)
+	GetNote(ctx context.Context, noteID string) (getting.SecureNote, error)
+	GetNoteMeta(ctx context.Context, noteID string) (getting.SecureNoteMeta, error)
+	ConsumeRead(ctx context.Context, noteID string, at time.Time) (getting.SecureNote, error)
+	RecordRead(ctx context.Context, noteID string, at time.Time) error
+	DeleteNote(ctx context.Context, noteID string) error
+	ReserveAttempt(ctx context.Context, noteID string, a getting.Attempt) (int, error)
+	ClearFailedAttempts(ctx context.Context, noteID string) error
+	GetNoteStatus(ctx context.Context, noteID string) (managing.SecureNoteStatus, error)
+	UpdateNote(ctx context.Context, noteID string, u managing.NoteUpdate) (managing.SecureNoteStatus, error)
+}
+
+// Run runs the conformance suite. newRepo must return an empty repository for every call.
+func Run(t *testing.T, newRepo func(t *testing.T) Repository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r Repository)
+	}{
+		{"CreateAndGetSealedNote", testCreateAndGetSealedNote},
+		{"CreateAndGetOpaqueNote", testCreateAndGetOpaqueNote},
+		{"CreateNoteWithTakenID", testCreateNoteWithTakenID},
+		{"GetMissingNote", testGetMissingNote},
+		{"GetNoteMeta", testGetNoteMeta},
+		{"GetMissingNoteMeta", testGetMissingNoteMeta},
+		{"ConsumeRead", testConsumeRead},
+		{"ConsumeLastRead", testConsumeLastRead},
+		{"ConsumeReadWithoutBudget", testConsumeReadWithoutBudget},
+		{"ConsumeReadMissingNote", testConsumeReadMissingNote},
+		{"ConsumeReadConcurrently", testConsumeReadConcurrently},
+		{"ConsumedNoteKeepsOnlyStatus", testConsumedNoteKeepsOnlyStatus},
+		{"RecordRead", testRecordRead},
+		{"RecordReadMissingNote", testRecordReadMissingNote},
+		{"DeleteNote", testDeleteNote},
+		{"DeleteMissingNote", testDeleteMissingNote},
+		{"IncrementNoteCounterConcurrently", testIncrementNoteCounterConcurrently},
+		{"ReserveAttempt", testReserveAttempt},
+		{"ReserveAttemptRejected", testReserveAttemptRejected},
+		{"ReserveAttemptConcurrently", testReserveAttemptConcurrently},
+		{"ReserveAttemptMissingNote", testReserveAttemptMissingNote},
+		{"ClearFailedAttempts", testClearFailedAttempts},
+		{"ClearFailedAttemptsMissingNote", testClearFailedAttemptsMissingNote},
+		{"GetNoteStatus", testGetNoteStatus},
+		{"GetMissingNoteStatus", testGetMissingNoteStatus},
+		{"UpdateNote", testUpdateNote},
+		{"UpdateNoteRemovesReadBudget", testUpdateNoteRemovesReadBudget},
+		{"UpdateMissingNote", testUpdateMissingNote},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+var (
+	ttl    = time.Date(2020, 3, 22, 16, 0, 0, 0, time.UTC).Unix()
+	readAt = time.Date(2020, 3, 22, 15, 30, 0, 0, time.UTC)
+)
+
+const managementTokenHash = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+func sealedNote(id string) creating.SecureNote {
+	return creating.SecureNote{
+		ID: id,
+		Sealed: security.SealedText{
+			Cipher:      security.CipherAES256GCM,
+			KDF:         security.KDFArgon2id,
+			KDFParams:   security.DefaultKDFParams,
+			PepperKeyID: "2020-06",
+			Salt:        []byte("0123456789abcdef"),
+			Nonce:       []byte("0123456789ab"),
+			Ciphertext:  []byte("sealed Hello World"),
+		},
+		TTL:      ttl,
+		MaxReads: 1,
+
+		ManagementTokenHash: managementTokenHash,
+		WebhookURL:          "https://hooks.example.com/read",
+		WebhookSecret:       "whsec",
+	}
+}
+
+func opaqueNote(id string) creating.SecureNote {
+	return creating.SecureNote{
+		ID:         id,
+		TTL:        ttl,
+		Opaque:     true,
+		Ciphertext: "U2FsdGVkX1+vupppZksvRf5pq5g5XjFRlipRkwB0K1Y=",
+		Metadata:   map[string]string{"alg": "AES-GCM"},
+	}
+}
+
+func testCreateAndGetSealedNote(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+
+	assert.NoError(t, err)
+	assert.Equal(t, getting.SecureNote{
+		ID:        sn.ID,
+		Sealed:    sn.Sealed,
+		TTL:       sn.TTL,
+		ReadsLeft: sn.MaxReads,
+
+		WebhookURL:    sn.WebhookURL,
+		WebhookSecret: sn.WebhookSecret,
+	}, got)
+}
+
+func testCreateAndGetOpaqueNote(t *testing.T, r Repository) {
+	sn := opaqueNote("qx2rx")
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+
+	assert.NoError(t, err)
+	assert.Equal(t, getting.SecureNote{
+		ID:         sn.ID,
+		TTL:        sn.TTL,
+		Opaque:     true,
+		Ciphertext: sn.Ciphertext,
+		Metadata:   sn.Metadata,
+	}, got)
+}
+
+func testCreateNoteWithTakenID(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+
+	err := r.CreateNote(context.TODO(), opaqueNote("qx2rx"))
+	assert.True(t, errors.Is(err, creating.ErrIDTaken), "got %v", err)
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.False(t, got.Opaque, "existing note must not be overwritten")
+}
+
+func testGetMissingNote(t *testing.T, r Repository) {
+	_, err := r.GetNote(context.TODO(), "missing")
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testGetNoteMeta(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("sealed")))
+	require.NoError(t, r.CreateNote(context.TODO(), opaqueNote("opaque")))
+
+	gotSealed, err := r.GetNoteMeta(context.TODO(), "sealed")
+	assert.NoError(t, err)
+	assert.Equal(t, getting.SecureNoteMeta{ID: "sealed", TTL: ttl, ReadsLeft: 1}, gotSealed)
+
+	gotOpaque, err := r.GetNoteMeta(context.TODO(), "opaque")
+	assert.NoError(t, err)
+	assert.Equal(t, getting.SecureNoteMeta{ID: "opaque", TTL: ttl, Opaque: true}, gotOpaque)
+}
+
+func testGetMissingNoteMeta(t *testing.T, r Repository) {
+	_, err := r.GetNoteMeta(context.TODO(), "missing")
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testConsumeRead(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	sn.MaxReads = 3
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+
+	got, err := r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+	assert.NoError(t, err)
+	assert.Equal(t, sn.ID, got.ID)
+	assert.Equal(t, sn.Sealed, got.Sealed)
+	assert.Equal(t, 2, got.ReadsLeft)
+
+	stored, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stored.ReadsLeft)
+
+	status, err := r.GetNoteStatus(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, status.ReadCount)
+	assert.Equal(t, readAt.Unix(), status.LastReadAt)
+}
+
+func testConsumeLastRead(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+
+	got, err := r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+	assert.NoError(t, err)
+	assert.Equal(t, sn.Sealed, got.Sealed)
+	assert.Equal(t, sn.WebhookURL, got.WebhookURL)
+	assert.Equal(t, sn.WebhookSecret, got.WebhookSecret)
+	assert.Equal(t, 0, got.ReadsLeft)
+
+	_, err = r.GetNote(context.TODO(), "qx2rx")
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testConsumeReadWithoutBudget(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), opaqueNote("qx2rx")))
+
+	_, err := r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+
+	_, err = r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err, "notes without a read budget must be kept")
+}
+
+func testConsumeReadMissingNote(t *testing.T, r Repository) {
+	_, err := r.ConsumeRead(context.TODO(), "missing", readAt)
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testConsumeReadConcurrently(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	sn.MaxReads = 3
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+
+	const readers = 20
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		readsLeft []int
+		start     = make(chan struct{})
+	)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			got, err := r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+			if err != nil && !errors.Is(err, getting.ErrNotFound) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				readsLeft = append(readsLeft, got.ReadsLeft)
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{2, 1, 0}, readsLeft)
+}
+
+func testConsumedNoteKeepsOnlyStatus(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+	_, err := r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+	require.NoError(t, err)
+
+	_, err = r.GetNoteMeta(context.TODO(), "qx2rx")
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+	err = r.RecordRead(context.TODO(), "qx2rx", readAt)
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+	_, err = r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 3, LastFailedBy: readAt.Unix(), At: readAt})
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+	_, err = r.UpdateNote(context.TODO(), "qx2rx", managing.NoteUpdate{ReadsLeft: intPtr(3)})
+	assert.True(t, errors.Is(err, managing.ErrNotFound), "got %v", err)
+
+	status, err := r.GetNoteStatus(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: managementTokenHash,
+		TTL:                 ttl,
+		ReadCount:           1,
+		LastReadAt:          readAt.Unix(),
+		Consumed:            true,
+	}, status)
+}
+
+func testRecordRead(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), opaqueNote("qx2rx")))
+
+	assert.NoError(t, r.RecordRead(context.TODO(), "qx2rx", readAt))
+	assert.NoError(t, r.RecordRead(context.TODO(), "qx2rx", readAt.Add(time.Minute)))
+
+	status, err := r.GetNoteStatus(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, status.ReadCount)
+	assert.Equal(t, readAt.Add(time.Minute).Unix(), status.LastReadAt)
+	assert.False(t, status.Consumed)
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, opaqueNote("qx2rx").Ciphertext, got.Ciphertext, "recording reads must keep the note intact")
+}
+
+func testRecordReadMissingNote(t *testing.T, r Repository) {
+	err := r.RecordRead(context.TODO(), "missing", readAt)
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testDeleteNote(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+
+	assert.NoError(t, r.DeleteNote(context.TODO(), "qx2rx"))
+
+	_, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testDeleteMissingNote(t *testing.T, r Repository) {
+	assert.NoError(t, r.DeleteNote(context.TODO(), "missing"))
+}
+
+func testReserveAttempt(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	sn.MaxFailedAttempts = 3
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+	at := time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+
+	first, err := r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 3, LastFailedBy: at.Unix(), At: at})
+	assert.NoError(t, err)
+	second, err := r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 3, LastFailedBy: at.Unix(), At: at.Add(time.Minute)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 2, second)
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got.MaxFailedAttempts)
+	assert.Equal(t, 2, got.FailedAttempts)
+	assert.Equal(t, at.Add(time.Minute).Unix(), got.LastFailedAt)
+	assert.Equal(t, sn.Sealed, got.Sealed, "reserving attempts must keep the note intact")
+}
+
+func testReserveAttemptRejected(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+	at := time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+	_, err := r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 2, LastFailedBy: at.Unix(), At: at})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		attempt getting.Attempt
+	}{
+		{"limit reached", getting.Attempt{Limit: 1, LastFailedBy: at.Unix(), At: at}},
+		{"backoff not elapsed", getting.Attempt{Limit: 2, LastFailedBy: at.Unix() - 1, At: at}},
+	}
+	for _, tt := range tests {
+		_, err := r.ReserveAttempt(context.TODO(), "qx2rx", tt.attempt)
+
+		assert.True(t, errors.Is(err, getting.ErrAttemptRejected), "%s: got %v", tt.name, err)
+	}
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.FailedAttempts)
+}
+
+func testReserveAttemptConcurrently(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+	at := time.Date(2020, 3, 22, 15, 0, 0, 0, time.UTC)
+
+	const (
+		guesses = 20
+		limit   = 5
+	)
+	var (
+		wg       sync.WaitGroup
+		reserved int32
+	)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: limit, LastFailedBy: at.Unix(), At: at})
+			switch {
+			case err == nil:
+				atomic.AddInt32(&reserved, 1)
+			case !errors.Is(err, getting.ErrAttemptRejected):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(limit), reserved)
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, limit, got.FailedAttempts)
+}
+
+func testReserveAttemptMissingNote(t *testing.T, r Repository) {
+	_, err := r.ReserveAttempt(context.TODO(), "missing", getting.Attempt{Limit: 3, LastFailedBy: readAt.Unix(), At: readAt})
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testClearFailedAttempts(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+	_, err := r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 1, LastFailedBy: readAt.Unix(), At: readAt})
+	require.NoError(t, err)
+
+	assert.NoError(t, r.ClearFailedAttempts(context.TODO(), "qx2rx"))
+
+	got, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Zero(t, got.FailedAttempts)
+	assert.Zero(t, got.LastFailedAt)
+	_, err = r.ReserveAttempt(context.TODO(), "qx2rx", getting.Attempt{Limit: 1, LastFailedBy: readAt.Unix(), At: readAt})
+	assert.NoError(t, err, "a cleared note must take guesses again")
+}
+
+func testClearFailedAttemptsMissingNote(t *testing.T, r Repository) {
+	err := r.ClearFailedAttempts(context.TODO(), "missing")
+
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testGetNoteStatus(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+
+	got, err := r.GetNoteStatus(context.TODO(), "qx2rx")
+
+	assert.NoError(t, err)
+	assert.Equal(t, managing.SecureNoteStatus{
+		ID:                  "qx2rx",
+		ManagementTokenHash: managementTokenHash,
+		TTL:                 ttl,
+		ReadsLeft:           1,
+	}, got)
+}
+
+func testGetMissingNoteStatus(t *testing.T, r Repository) {
+	_, err := r.GetNoteStatus(context.TODO(), "missing")
+
+	assert.True(t, errors.Is(err, managing.ErrNotFound), "got %v", err)
+}
+
+func testUpdateNote(t *testing.T, r Repository) {
+	sn := sealedNote("qx2rx")
+	require.NoError(t, r.CreateNote(context.TODO(), sn))
+	newTTL := ttl + 3600
+
+	got, err := r.UpdateNote(context.TODO(), "qx2rx", managing.NoteUpdate{TTL: &newTTL, ReadsLeft: intPtr(5)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, newTTL, got.TTL)
+	assert.Equal(t, 5, got.ReadsLeft)
+
+	stored, err := r.GetNote(context.TODO(), "qx2rx")
+	assert.NoError(t, err)
+	assert.Equal(t, newTTL, stored.TTL)
+	assert.Equal(t, 5, stored.ReadsLeft)
+	assert.Equal(t, sn.Sealed, stored.Sealed, "updating must keep the note intact")
+}
+
+func testUpdateNoteRemovesReadBudget(t *testing.T, r Repository) {
+	require.NoError(t, r.CreateNote(context.TODO(), sealedNote("qx2rx")))
+
+	got, err := r.UpdateNote(context.TODO(), "qx2rx", managing.NoteUpdate{ReadsLeft: intPtr(0)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, got.ReadsLeft)
+	assert.Equal(t, ttl, got.TTL)
+
+	_, err = r.ConsumeRead(context.TODO(), "qx2rx", readAt)
+	assert.True(t, errors.Is(err, getting.ErrNotFound), "got %v", err)
+}
+
+func testUpdateMissingNote(t *testing.T, r Repository) {
+	_, err := r.UpdateNote(context.TODO(), "missing", managing.NoteUpdate{ReadsLeft: intPtr(1)})
+
+	assert.True(t, errors.Is(err, managing.ErrNotFound), "got %v", err)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func testIncrementNoteCounterConcurrently(t *testing.T, r Repository) {
+	const writers = 20
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[int]bool)
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := r.IncrementNoteCounter(context.TODO())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			seen[n] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, writers, "counter values must be unique")
+	for n := 1; n <= writers; n++ {
+		assert.True(t, seen[n], "counter value %d missing", n)
+	}
+}
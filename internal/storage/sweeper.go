@@ -0,0 +1,30 @@
+// Package storage contains helpers shared by note storage backends.
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiredDeleter is implemented by backends that have no native TTL support
+type ExpiredDeleter interface {
+	DeleteExpired(ctx context.Context) (deleted int, err error)
+}
+
+// RunSweeper deletes expired notes every interval until ctx is done.
+// Errors are passed to onError and do not stop the sweeper.
+func RunSweeper(ctx context.Context, d ExpiredDeleter, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.DeleteExpired(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
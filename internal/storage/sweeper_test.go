@@ -0,0 +1,43 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projects/secure-notes/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSweeper(t *testing.T) {
+	// given
+	d := &countingDeleter{err: errors.New("disk full")}
+	var errs int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// when
+	go func() {
+		storage.RunSweeper(ctx, d, time.Millisecond, func(error) { atomic.AddInt32(&errs, 1) })
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// then
+	assert.True(t, atomic.LoadInt32(&d.calls) > 1)
+	assert.Equal(t, atomic.LoadInt32(&d.calls), atomic.LoadInt32(&errs))
+}
+
+type countingDeleter struct {
+	calls int32
+	err   error
+}
+
+func (d *countingDeleter) DeleteExpired(ctx context.Context) (int, error) {
+	atomic.AddInt32(&d.calls, 1)
+	return 0, d.err
+}